@@ -1,8 +1,13 @@
-module hackatime-lsp
+module github.com/espcaa/hackatime-zed/hackatime-lsp
 
 go 1.25.4
 
-require github.com/tliron/glsp v0.1.1
+require (
+	github.com/sourcegraph/jsonrpc2 v0.2.0
+	github.com/tliron/glsp v0.1.1
+	golang.org/x/net v0.17.0
+	golang.org/x/sys v0.14.0
+)
 
 require (
 	github.com/aymanbagabas/go-osc52 v1.0.3 // indirect
@@ -16,12 +21,10 @@ require (
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
 	github.com/sasha-s/go-deadlock v0.3.1 // indirect
-	github.com/sourcegraph/jsonrpc2 v0.2.0 // indirect
 	github.com/tliron/kutil v0.1.67 // indirect
 	github.com/vmihailenco/msgpack/v5 v5.3.5 // indirect
 	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
 	golang.org/x/crypto v0.15.0 // indirect
-	golang.org/x/net v0.17.0 // indirect
-	golang.org/x/sys v0.14.0 // indirect
 	golang.org/x/term v0.14.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
 )
@@ -0,0 +1,100 @@
+package backend
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/logging"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+type Backend struct {
+	Name  string
+	Queue *hackatime.Queue
+}
+
+type Backends []*Backend
+
+func Open(batchInterval time.Duration, newSender func(backend config.Backend) hackatime.SendFunc) Backends {
+	var backends Backends
+	for _, cfg := range config.Backends() {
+		if cfg.ApiUrl == "" || cfg.ApiKey == "" {
+			slog.Warn("BackendSkipped", "backend", cfg.Name, "result", "api_url and api_key are both required")
+			continue
+		}
+		backends = append(backends, open(cfg, batchInterval, newSender(cfg)))
+	}
+	return backends
+}
+
+func open(cfg config.Backend, batchInterval time.Duration, send hackatime.SendFunc) *Backend {
+	b := &Backend{Name: cfg.Name}
+	b.Queue = hackatime.NewQueue(func(heartbeats []hackatime.Heartbeat) error {
+		err := send(heartbeats)
+		var partial *hackatime.PartialError
+		switch _, retry := hackatime.RetryDelay(err); {
+		case errors.As(err, &partial) && len(partial.Retry) < len(heartbeats):
+			slog.Warn("BackendHeartbeatsPartiallySent", "backend", b.Name, "heartbeats", len(heartbeats), "rejected", len(partial.Rejected), "retrying", len(partial.Retry), "result", partial)
+			logging.Rejected(partial.Rejected, "backend", b.Name)
+		case err == nil:
+			slog.Info("BackendHeartbeatsSent", "backend", b.Name, "heartbeats", len(heartbeats), "result", "ok")
+		case retry:
+			slog.Debug("BackendHeartbeatsDeferred", "backend", b.Name, "heartbeats", len(heartbeats), "result", err)
+		default:
+			slog.Error("BackendHeartbeatsFailed", "backend", b.Name, "heartbeats", len(heartbeats), "result", err)
+		}
+		return err
+	})
+	b.Queue.Configure(batchInterval, config.Int("queue_size", hackatime.DefaultQueueSize))
+	if path := config.BackendQueueFilePath(cfg.Name); path != "" {
+		b.Queue.SpillTo(hackatime.NewStore(path), config.Int("queue_memory_limit", hackatime.DefaultMemoryLimit))
+	}
+	b.Queue.OnConnectivity(func(online bool, synced int) {
+		if online {
+			slog.Info("BackendBackOnline", "backend", b.Name, "synced", synced)
+			return
+		}
+		slog.Warn("BackendOffline", "backend", b.Name, "queued", b.Queue.Len())
+	})
+	return b
+}
+
+func (backends Backends) Add(hb hackatime.Heartbeat) {
+	for _, b := range backends {
+		b.Queue.Add(hb)
+	}
+}
+
+func (backends Backends) Pause() {
+	for _, b := range backends {
+		b.Queue.Pause()
+	}
+}
+
+func (backends Backends) Resume() {
+	for _, b := range backends {
+		b.Queue.Resume()
+	}
+}
+
+func (backends Backends) Close() {
+	for _, b := range backends {
+		if err := b.Queue.Close(); err != nil {
+			slog.Error("BackendCloseFailed", "backend", b.Name, "error", err)
+		}
+	}
+}
+
+func (backends Backends) Persist() int {
+	persisted := 0
+	for _, b := range backends {
+		queued, err := b.Queue.Persist()
+		if err != nil {
+			slog.Error("BackendPersistFailed", "backend", b.Name, "queued", queued, "error", err)
+		}
+		persisted += queued
+	}
+	return persisted
+}
@@ -0,0 +1,102 @@
+package command
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+func cliOptions() hackatime.CLIOptions {
+	return hackatime.CLIOptions{
+		ApiKey:                config.ApiKey(),
+		ApiUrl:                config.ApiUrl(),
+		ExcludeUnknownProject: config.Settings{}.Bool("exclude_unknown_project", false),
+		ConfigFile:            config.FilePath(),
+		LogFile:               config.LogFilePath(),
+		Timeout:               time.Duration(config.Int("cli_timeout", 0)) * time.Second,
+	}
+}
+
+func newSender(cliFlag string) (hackatime.SendFunc, error) {
+	send := hackatime.NewAPISender(newClient)
+	if !(config.Settings{}).Bool("direct_api", false) {
+		cliPath, err := findCLI(cliFlag)
+		if err != nil {
+			return nil, err
+		}
+		send = hackatime.NewCLISender(hackatime.ExecRunner{}, cliPath, cliOptions)
+	}
+
+	forBackend := backendSender(cliFlag)
+	return hackatime.PerAccount(send, func(name string) hackatime.SendFunc {
+		account, ok := config.FindAccount(name)
+		if !ok {
+			return send
+		}
+		return forBackend(account.Destination())
+	}), nil
+}
+
+func backendSender(cliFlag string) func(b config.Backend) hackatime.SendFunc {
+	direct := (config.Settings{}).Bool("direct_api", false)
+	var cliPath string
+	var cliErr error
+	if !direct {
+		cliPath, cliErr = findCLI(cliFlag)
+	}
+
+	return func(b config.Backend) hackatime.SendFunc {
+		if direct {
+			return hackatime.NewAPISender(func() (*hackatime.Client, error) {
+				return newClientFor(b.ApiUrl, b.ApiKey)
+			})
+		}
+		if cliErr != nil {
+			return func([]hackatime.Heartbeat) error { return cliErr }
+		}
+		return hackatime.NewCLISender(hackatime.ExecRunner{}, cliPath, func() hackatime.CLIOptions {
+			options := cliOptions()
+			options.ApiUrl = b.ApiUrl
+			options.ApiKey = b.ApiKey
+			return options
+		})
+	}
+}
+
+func findCLI(path string) (string, error) {
+	if path == "" {
+		path = defaultCLIPath()
+	}
+	if path == "" {
+		return "", errors.New("wakatime-cli not found, pass --wakatime-cli")
+	}
+	return path, hackatime.ValidateCLIPath(path)
+}
+
+func defaultCLIPath() string {
+	name := "wakatime-cli"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	if path, err := exec.LookPath(name); err == nil {
+		return path
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	for _, candidate := range []string{name, "wakatime-cli-" + runtime.GOOS + "-" + runtime.GOARCH + filepath.Ext(name)} {
+		path := filepath.Join(homeDir, ".wakatime", candidate)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
@@ -0,0 +1,22 @@
+package command
+
+var commands = map[string]func(args []string) int{
+	"summaries":   Summaries,
+	"leaderboard": Leaderboard,
+	"report":      Report,
+	"export":      Export,
+	"flush":       Flush,
+	"today":       Today,
+	"config":      Config,
+	"send-test":   SendTest,
+	"self-update": SelfUpdate,
+	"log":         Log,
+	"replay":      Replay,
+	"status":      Status,
+	"daemon":      Daemon,
+}
+
+func Lookup(name string) (func(args []string) int, bool) {
+	run, exists := commands[name]
+	return run, exists
+}
@@ -0,0 +1,85 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+)
+
+func Config(args []string) int {
+	if len(args) == 0 || args[0] != "set" {
+		fmt.Fprintln(os.Stderr, "usage: hackatime-ls config set [--section settings] key=value...")
+		return 2
+	}
+
+	flags := flag.NewFlagSet("config set", flag.ContinueOnError)
+	section := flags.String("section", "", "Section to write to (default: where the key already is, else settings)")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: hackatime-ls config set [--section settings] key=value...")
+		return 2
+	}
+
+	entries := make([][2]string, 0, flags.NArg())
+	for _, arg := range flags.Args() {
+		key, value, err := parseEntry(arg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "hackatime-ls config:", err)
+			return 2
+		}
+		entries = append(entries, [2]string{key, value})
+	}
+
+	sections := config.Read()
+	var keys []string
+	for _, entry := range entries {
+		key, value := entry[0], entry[1]
+		target := strings.ToLower(*section)
+		if target == "" {
+			target = "settings"
+			if _, exists := sections["hackatime"][key]; exists {
+				target = "hackatime"
+			}
+		}
+
+		if err := config.Set(target, key, value); err != nil {
+			fmt.Fprintln(os.Stderr, "hackatime-ls config:", err)
+			return 1
+		}
+		keys = append(keys, key)
+	}
+
+	fmt.Printf("Updated %s (%s)\n", config.FilePath(), strings.Join(keys, ", "))
+	return 0
+}
+
+func parseEntry(arg string) (string, string, error) {
+	key, value, found := strings.Cut(arg, "=")
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	if !found || key == "" {
+		return "", "", fmt.Errorf("invalid %q, expected key=value", arg)
+	}
+	if strings.ContainsAny(key, " \t[]#;") {
+		return "", "", fmt.Errorf("invalid key %q", key)
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return "", "", fmt.Errorf("%s: value can't span multiple lines", key)
+	}
+
+	switch key {
+	case "api_key":
+		if err := config.ValidateApiKey(value); err != nil {
+			return "", "", err
+		}
+	case "api_url":
+		if err := config.ValidateApiUrl(value); err != nil {
+			return "", "", err
+		}
+	}
+	return key, value, nil
+}
@@ -0,0 +1,201 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/backend"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/daemon"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/logging"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+const defaultDaemonIdleTimeout = 30 * time.Minute
+
+func Daemon(args []string) int {
+	flags := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	cliFlag := flags.String("wakatime-cli", "", "Path to wakatime-cli (default: wakatime-cli on PATH or in ~/.wakatime)")
+	socket := flags.String("socket", "", "Socket to listen on (default ~/.wakatime/hackatime-zed.sock)")
+	idleTimeout := flags.Duration("idle-timeout", defaultDaemonIdleTimeout, "Exit after this long without any connected editor, 0 to keep running")
+	logLevel := flags.String("log-level", "info", "Log level: debug, info, warn or error")
+	health := flags.Bool("health", false, "Check whether a daemon is running on the socket and print its health")
+	asJSON := flags.Bool("json", false, "With --health, print the health as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	path := *socket
+	if path == "" {
+		path = config.DaemonSocketPath()
+	}
+	if *health {
+		return daemonHealth(path, *asJSON)
+	}
+
+	if err := logging.Setup(logging.Options{
+		Level: *logLevel,
+		Rotation: logging.Rotation{
+			MaxSize:    int64(config.Int("log_max_size_mb", logging.DefaultMaxSizeMB)) * 1024 * 1024,
+			MaxBackups: config.Int("log_max_backups", logging.DefaultMaxBackups),
+			MaxAge:     time.Duration(config.Int("log_max_age_days", logging.DefaultMaxAgeDays)) * 24 * time.Hour,
+		},
+	}); err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls daemon: invalid --log-level:", err)
+		return 2
+	}
+
+	send, err := newSender(*cliFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls daemon:", err)
+		return 1
+	}
+
+	listener, err := daemon.Listen(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls daemon:", err)
+		return 1
+	}
+
+	var history *hackatime.Store
+	if historyPath := config.HistoryFilePath(); historyPath != "" && (config.Settings{}).Bool("local_history", true) {
+		history = hackatime.NewStore(historyPath)
+	}
+
+	var d *daemon.Daemon
+	queue := hackatime.NewQueue(func(heartbeats []hackatime.Heartbeat) error {
+		err := send(heartbeats)
+		d.RecordSend(heartbeats, err)
+		var partial *hackatime.PartialError
+		if errors.As(err, &partial) && len(partial.Retry) < len(heartbeats) {
+			accepted := partial.Accepted(heartbeats)
+			slog.Warn("HeartbeatsPartiallySent", "heartbeats", len(heartbeats), "sent", len(accepted), "rejected", len(partial.Rejected), "retrying", len(partial.Retry), "result", partial)
+			logging.Rejected(partial.Rejected)
+			if history != nil {
+				history.Append(accepted)
+			}
+			return err
+		}
+		if wait, limited := hackatime.RetryAfter(err); limited {
+			slog.Warn("RateLimited", "heartbeats", len(heartbeats), "retry_after", wait.String())
+			return err
+		}
+		if _, retry := hackatime.RetryDelay(err); retry {
+			slog.Debug("HeartbeatsDeferred", "heartbeats", len(heartbeats), "result", err)
+			return err
+		}
+		if err != nil {
+			slog.Error("HeartbeatsFailed", "heartbeats", len(heartbeats), "result", err)
+			for _, hb := range heartbeats {
+				slog.Info("HeartbeatFailed", "entity", hb.Entity, "project", hb.AlternateProject, "result", err.Error(), "heartbeat", hb)
+			}
+			return err
+		}
+		slog.Info("HeartbeatsSent", "heartbeats", len(heartbeats), "result", "ok")
+		if history != nil {
+			history.Append(heartbeats)
+		}
+		return nil
+	})
+	batchInterval := time.Duration(config.Int("batch_interval", int(hackatime.DefaultBatchInterval/time.Second))) * time.Second
+	queue.OnConnectivity(func(online bool, synced int) {
+		if online {
+			slog.Info("BackOnline", "synced", synced)
+			return
+		}
+		slog.Warn("Offline", "queued", queue.Len())
+	})
+	batchInterval = max(batchInterval, 5*time.Second)
+	queue.Configure(batchInterval, config.Int("queue_size", hackatime.DefaultQueueSize))
+	if queuePath := config.QueueFilePath(); queuePath != "" {
+		queue.SpillTo(hackatime.NewStore(queuePath), config.Int("queue_memory_limit", hackatime.DefaultMemoryLimit))
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		slog.Info("Signal", "signal", sig.String(), "queued", queue.Len())
+		listener.Close()
+	}()
+
+	slog.Info("Daemon", "socket", path, "pid", os.Getpid())
+	backends := backend.Open(batchInterval, backendSender(*cliFlag))
+	d = daemon.New(queue, backends)
+	err = d.Serve(listener, *idleTimeout)
+	if err := queue.Close(); err != nil {
+		slog.Error("QueueCloseFailed", "error", err)
+	}
+	backends.Close()
+	slog.Info("DaemonStopped", "queued", queue.Len(), "handed_over", d.HandedOver())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls daemon:", err)
+		return 1
+	}
+	return 0
+}
+
+func daemonHealth(path string, asJSON bool) int {
+	health, err := daemon.Ping(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls daemon: no daemon is running on "+path+":", err)
+		return 1
+	}
+
+	if asJSON {
+		data, err := json.MarshalIndent(health, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "hackatime-ls daemon:", err)
+			return 1
+		}
+		fmt.Println(string(data))
+		return 0
+	}
+
+	fmt.Printf("hackatime-ls daemon %s is running (pid %d, up %s)\n", health.Version, health.Pid, hackatime.FormatDuration(time.Duration(health.Uptime*float64(time.Second))))
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer table.Flush()
+
+	fmt.Fprintf(table, "Windows:\t%d\n", health.Clients)
+	queued := fmt.Sprint(health.Queued)
+	if health.Spilled {
+		queued += " (some on disk)"
+	}
+	fmt.Fprintf(table, "Queued:\t%s\n", queued)
+	fmt.Fprintf(table, "Received:\t%d (%d throttled, %d from other windows)\n", health.Received, health.Throttled, health.Deduped)
+	fmt.Fprintf(table, "Sent:\t%d (%d failed, %d timeouts)\n", health.Sent, health.Failed, health.Timeouts)
+	lastErrorIsNewer := health.LastErrorAt != nil && (health.LastSent == nil || health.LastErrorAt.After(*health.LastSent))
+	switch {
+	case lastErrorIsNewer:
+		fmt.Fprintf(table, "Last send:\tfailed %s: %s\n", ago(*health.LastErrorAt), health.LastError)
+	case health.LastSent != nil:
+		fmt.Fprintf(table, "Last send:\tok, %s\n", ago(*health.LastSent))
+	default:
+		fmt.Fprintln(table, "Last send:\tnothing sent yet")
+	}
+	if health.Windows != (daemon.Counts{}) {
+		fmt.Fprintf(table, "In windows:\t%s\n", formatCounts(health.Windows))
+	}
+
+	for _, session := range health.Sessions {
+		fmt.Fprintf(table, "\nWindow %d:\t%s\n", session.Pid, orUnknown(session.Workspace))
+		fmt.Fprintf(table, "  Connected:\t%s, %d heartbeats received\n", ago(session.ConnectedAt), session.Received)
+		if session.Metrics != nil {
+			fmt.Fprintf(table, "  Metrics:\t%s\n", formatCounts(*session.Metrics))
+		}
+	}
+	return 0
+}
+
+func formatCounts(counts daemon.Counts) string {
+	return fmt.Sprintf("%d queued, %d sent, %d failed, %d deduped, %d throttled, %d dropped, %d timeouts",
+		counts.Queued, counts.Sent, counts.Failed, counts.Deduped, counts.Throttled, counts.Dropped, counts.Timeouts)
+}
@@ -0,0 +1,163 @@
+package command
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+type exportedDuration struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Seconds  float64   `json:"seconds"`
+	Project  string    `json:"project"`
+	Language string    `json:"language"`
+	Category string    `json:"category"`
+}
+
+func Export(args []string) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", "csv", "csv or json")
+	what := flags.String("what", "durations", "heartbeats or durations")
+	rangeFlag := flags.String("range", "", "Only export the last 7d, 4w, ... (default everything)")
+	output := flags.String("output", "", "Write to this file instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "csv" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "hackatime-ls export: invalid --format %q, expected csv or json\n", *format)
+		return 2
+	}
+	if *what != "heartbeats" && *what != "durations" {
+		fmt.Fprintf(os.Stderr, "hackatime-ls export: invalid --what %q, expected heartbeats or durations\n", *what)
+		return 2
+	}
+
+	var since time.Time
+	if *rangeFlag != "" {
+		days, err := parseRange(*rangeFlag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "hackatime-ls export:", err)
+			return 2
+		}
+		since = startOfDay(time.Now().AddDate(0, 0, -(days - 1)))
+	}
+
+	heartbeats, err := readHistory(since)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls export:", err)
+		return 1
+	}
+
+	w := io.Writer(os.Stdout)
+	if *output != "" {
+		file, err := os.Create(config.ExpandHome(*output))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "hackatime-ls export:", err)
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if *what == "heartbeats" {
+		err = exportHeartbeats(w, *format, heartbeats)
+	} else {
+		timeout := time.Duration(config.Int("keystroke_timeout", int(hackatime.DefaultKeystrokeTimeout/time.Minute))) * time.Minute
+		err = exportDurations(w, *format, hackatime.Durations(heartbeats, timeout))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls export:", err)
+		return 1
+	}
+	return 0
+}
+
+func readHistory(since time.Time) ([]hackatime.Heartbeat, error) {
+	path := config.HistoryFilePath()
+	if path == "" {
+		return nil, errors.New("could not determine home directory")
+	}
+
+	heartbeats, err := hackatime.NewStore(path).Read(func(hb hackatime.Heartbeat) bool {
+		return hb.Time >= float64(since.Unix())
+	})
+	slices.SortStableFunc(heartbeats, func(a, b hackatime.Heartbeat) int {
+		return cmp.Compare(a.Time, b.Time)
+	})
+	return heartbeats, err
+}
+
+func exportHeartbeats(w io.Writer, format string, heartbeats []hackatime.Heartbeat) error {
+	if format == "json" {
+		return writeJSON(w, heartbeats)
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"time", "entity", "project", "language", "category", "is_write", "lines", "lineno", "line_additions", "line_deletions"})
+	for _, hb := range heartbeats {
+		writer.Write([]string{
+			time.UnixMilli(int64(hb.Time * 1000)).Format(time.RFC3339),
+			hb.Entity,
+			hb.AlternateProject,
+			hackatime.WakatimeLanguage(hb.Language),
+			hb.Category,
+			strconv.FormatBool(hb.IsWrite),
+			strconv.Itoa(hb.Lines),
+			strconv.Itoa(hb.LineNumber),
+			strconv.Itoa(hb.LineAdditions),
+			strconv.Itoa(hb.LineDeletions),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func exportDurations(w io.Writer, format string, durations []hackatime.Duration) error {
+	exported := make([]exportedDuration, 0, len(durations))
+	for _, d := range durations {
+		exported = append(exported, exportedDuration{
+			Start:    d.Start,
+			End:      d.End(),
+			Seconds:  d.Duration.Seconds(),
+			Project:  d.Project,
+			Language: hackatime.WakatimeLanguage(d.Language),
+			Category: d.Category,
+		})
+	}
+	if format == "json" {
+		return writeJSON(w, exported)
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"start", "end", "seconds", "project", "language", "category"})
+	for _, d := range exported {
+		writer.Write([]string{
+			d.Start.Format(time.RFC3339),
+			d.End.Format(time.RFC3339),
+			strconv.FormatFloat(d.Seconds, 'f', 0, 64),
+			d.Project,
+			d.Language,
+			d.Category,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeJSON(w io.Writer, value any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
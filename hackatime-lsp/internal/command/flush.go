@@ -0,0 +1,89 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+func Flush(args []string) int {
+	flags := flag.NewFlagSet("flush", flag.ContinueOnError)
+	cliFlag := flags.String("wakatime-cli", "", "Path to wakatime-cli (default: wakatime-cli on PATH or in ~/.wakatime)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	send, err := newSender(*cliFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls flush:", err)
+		return 1
+	}
+
+	path := config.QueueFilePath()
+	if path == "" {
+		fmt.Fprintln(os.Stderr, "hackatime-ls flush: could not determine home directory")
+		return 1
+	}
+
+	queue := hackatime.NewStore(path)
+
+	var history *hackatime.Store
+	if historyPath := config.HistoryFilePath(); historyPath != "" && (config.Settings{}).Bool("local_history", true) {
+		history = hackatime.NewStore(historyPath)
+	}
+
+	sent, rejected := 0, 0
+	for {
+		batch, err := queue.Take(config.Int("queue_size", hackatime.DefaultQueueSize))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "hackatime-ls flush:", err)
+			return 1
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		err = send(batch)
+		var partial *hackatime.PartialError
+		if errors.As(err, &partial) {
+			accepted := partial.Accepted(batch)
+			sent += len(accepted)
+			rejected += len(partial.Rejected)
+			for _, rejection := range partial.Rejected {
+				fmt.Fprintf(os.Stderr, "hackatime-ls flush: rejected %s: %s\n", rejection.Heartbeat.Entity, rejection.Reason)
+			}
+			if history != nil {
+				history.Append(accepted)
+			}
+			if len(partial.Retry) == 0 {
+				continue
+			}
+			batch = partial.Retry
+		}
+		if err != nil {
+			queue.Append(batch)
+			fmt.Fprintf(os.Stderr, "hackatime-ls flush: sent %d heartbeats, %d still queued: %v\n", sent, queued(queue), err)
+			return 1
+		}
+		sent += len(batch)
+		if history != nil {
+			history.Append(batch)
+		}
+	}
+
+	fmt.Printf("Sent %d heartbeats\n", sent)
+	if rejected > 0 {
+		fmt.Printf("%d heartbeats were rejected by the server\n", rejected)
+		return 1
+	}
+	return 0
+}
+
+func queued(store *hackatime.Store) int {
+	heartbeats, _ := store.Read(nil)
+	return len(heartbeats)
+}
@@ -0,0 +1,70 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+func Leaderboard(args []string) int {
+	flags := flag.NewFlagSet("leaderboard", flag.ContinueOnError)
+	period := flags.String("period", hackatime.LeaderboardDaily, "daily or weekly")
+	top := flags.Int("top", 10, "How many entries to print")
+	asJSON := flags.Bool("json", false, "Print the raw API response")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *period != hackatime.LeaderboardDaily && *period != hackatime.LeaderboardWeekly {
+		fmt.Fprintf(os.Stderr, "hackatime-ls leaderboard: invalid --period %q, expected daily or weekly\n", *period)
+		return 2
+	}
+
+	client, err := newClient()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls leaderboard:", err)
+		return 1
+	}
+
+	leaderboard, rank, err := client.LeaderboardRank(context.Background(), *period)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls leaderboard:", err)
+		return 1
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(leaderboard)
+		return 0
+	}
+	printLeaderboard(os.Stdout, leaderboard, rank, *top)
+	return 0
+}
+
+func printLeaderboard(w io.Writer, leaderboard *hackatime.Leaderboard, rank *hackatime.LeaderboardRank, top int) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, entry := range leaderboard.Entries {
+		if i >= top {
+			break
+		}
+		fmt.Fprintf(table, "%d.\t%s\t%s\n", entry.Rank, entry.User.Username, formatSeconds(entry.TotalSeconds))
+	}
+	table.Flush()
+
+	if rank == nil {
+		fmt.Fprintf(w, "\nYou're not on the %s leaderboard yet.\n", leaderboard.Period)
+		return
+	}
+	fmt.Fprintf(w, "\nYou're #%d of %d on the %s leaderboard with %s.\n", rank.Rank, rank.Of, leaderboard.Period, formatSeconds(rank.TotalSeconds))
+}
+
+func formatSeconds(seconds float64) string {
+	return hackatime.FormatDuration(time.Duration(seconds * float64(time.Second)))
+}
@@ -0,0 +1,213 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/logging"
+)
+
+const followInterval = 500 * time.Millisecond
+
+type logFilter struct {
+	level slog.Level
+	event string
+	raw   bool
+}
+
+type logField struct {
+	key   string
+	value json.RawMessage
+}
+
+func Log(args []string) int {
+	flags := flag.NewFlagSet("log", flag.ContinueOnError)
+	tail := flags.Int("tail", 50, "Number of matching lines to show, 0 for all")
+	levelFlag := flags.String("level", "debug", "Only show this level and above: debug, info, warn or error")
+	event := flags.String("event", "", "Only show events whose name contains this, e.g. Heartbeat")
+	file := flags.String("file", "", "Log file to read (default ~/hackatime-zed.log)")
+	follow := flags.Bool("follow", false, "Keep printing new lines as they are written")
+	raw := flags.Bool("json", false, "Print the matching lines as-is")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	filter := logFilter{event: strings.ToLower(*event), raw: *raw}
+	if err := filter.level.UnmarshalText([]byte(*levelFlag)); err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls log: invalid --level:", err)
+		return 2
+	}
+
+	path := logging.DefaultFile()
+	if *file != "" {
+		path = config.ExpandHome(*file)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls log:", err)
+		return 1
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	var lines []string
+	var partial string
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			partial = line
+			break
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "hackatime-ls log:", err)
+			return 1
+		}
+		if text, ok := filter.format(line); ok {
+			lines = append(lines, text)
+			if *tail > 0 && len(lines) > *tail {
+				lines = lines[1:]
+			}
+		}
+	}
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+
+	if *follow {
+		return followLog(path, f, partial, filter)
+	}
+	return 0
+}
+
+func followLog(path string, f *os.File, partial string, filter logFilter) int {
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadString('\n')
+		partial += line
+		if err == nil {
+			if text, ok := filter.format(partial); ok {
+				fmt.Println(text)
+			}
+			partial = ""
+			continue
+		}
+		if err != io.EOF {
+			fmt.Fprintln(os.Stderr, "hackatime-ls log:", err)
+			return 1
+		}
+
+		time.Sleep(followInterval)
+		offset, _ := f.Seek(0, io.SeekCurrent)
+		if info, err := os.Stat(path); err == nil && info.Size() < offset {
+			f.Close()
+			if f, err = os.Open(path); err != nil {
+				fmt.Fprintln(os.Stderr, "hackatime-ls log:", err)
+				return 1
+			}
+			partial = ""
+		}
+		reader.Reset(f)
+	}
+}
+
+func (filter logFilter) format(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", false
+	}
+
+	fields, err := parseLogLine(line)
+	if err != nil {
+		return line, filter.event == ""
+	}
+
+	var timestamp, levelName, event string
+	var rest []logField
+	for _, field := range fields {
+		var value string
+		if json.Unmarshal(field.value, &value) != nil {
+			rest = append(rest, field)
+			continue
+		}
+		switch field.key {
+		case "timestamp":
+			timestamp = value
+		case "level":
+			levelName = value
+		case "event":
+			event = value
+		default:
+			rest = append(rest, field)
+		}
+	}
+
+	var level slog.Level
+	if level.UnmarshalText([]byte(levelName)) == nil && level < filter.level {
+		return "", false
+	}
+	if filter.event != "" && !strings.Contains(strings.ToLower(event), filter.event) {
+		return "", false
+	}
+	if filter.raw {
+		return line, true
+	}
+
+	if parsed, err := time.Parse(time.RFC3339Nano, timestamp); err == nil {
+		timestamp = parsed.Local().Format(time.DateTime)
+	}
+	out := fmt.Sprintf("%s %-5s %s", timestamp, levelName, event)
+	for _, field := range rest {
+		out += " " + field.key + "=" + formatLogValue(field.value)
+	}
+	return out, true
+}
+
+func parseLogLine(line string) ([]logField, error) {
+	decoder := json.NewDecoder(strings.NewReader(line))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, errors.New("not a JSON object")
+	}
+
+	var fields []logField
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := token.(string)
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, logField{key: key, value: value})
+	}
+	return fields, nil
+}
+
+func formatLogValue(value json.RawMessage) string {
+	var text string
+	if json.Unmarshal(value, &text) != nil {
+		var compact bytes.Buffer
+		if json.Compact(&compact, value) != nil {
+			return string(value)
+		}
+		return compact.String()
+	}
+	if text == "" || strings.ContainsAny(text, " \t\n\"=") {
+		return strconv.Quote(text)
+	}
+	return text
+}
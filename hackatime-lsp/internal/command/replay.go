@@ -0,0 +1,201 @@
+package command
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/logging"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+type loggedEvent struct {
+	Event     string               `json:"event"`
+	Entity    string               `json:"entity"`
+	Time      float64              `json:"time"`
+	Result    string               `json:"result"`
+	Heartbeat *hackatime.Heartbeat `json:"heartbeat"`
+}
+
+func Replay(args []string) int {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	rangeFlag := flags.String("range", "7d", "How far back to look, e.g. 1d, 7d or 4w")
+	cliFlag := flags.String("wakatime-cli", "", "Path to wakatime-cli (default: wakatime-cli on PATH or in ~/.wakatime)")
+	file := flags.String("file", "", "Log file to read (default ~/hackatime-zed.log)")
+	dryRun := flags.Bool("dry-run", false, "List the heartbeats that would be sent without sending them")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	days, err := parseRange(*rangeFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls replay:", err)
+		return 2
+	}
+	since := startOfDay(time.Now().AddDate(0, 0, -(days - 1)))
+
+	path := logging.DefaultFile()
+	if *file != "" {
+		path = config.ExpandHome(*file)
+	}
+
+	heartbeats, err := unconfirmedHeartbeats(path, since)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls replay:", err)
+		return 1
+	}
+	if len(heartbeats) == 0 {
+		fmt.Println("Nothing to replay")
+		return 0
+	}
+
+	if *dryRun {
+		for _, hb := range heartbeats {
+			fmt.Printf("%s  %s  %s\n", time.UnixMilli(int64(hb.Time*1000)).Format(time.DateTime), orUnknown(hb.AlternateProject), hb.Entity)
+		}
+		fmt.Printf("%d heartbeats would be sent\n", len(heartbeats))
+		return 0
+	}
+
+	send, err := newSender(*cliFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls replay:", err)
+		return 1
+	}
+
+	var history *hackatime.Store
+	if historyPath := config.HistoryFilePath(); historyPath != "" && (config.Settings{}).Bool("local_history", true) {
+		history = hackatime.NewStore(historyPath)
+	}
+
+	batchSize := config.Int("queue_size", hackatime.DefaultQueueSize)
+	sent := 0
+	for batch := range slices.Chunk(heartbeats, batchSize) {
+		err := send(batch)
+		var partial *hackatime.PartialError
+		if errors.As(err, &partial) && len(partial.Retry) == 0 {
+			accepted := partial.Accepted(batch)
+			sent += len(accepted)
+			for _, rejection := range partial.Rejected {
+				fmt.Fprintf(os.Stderr, "hackatime-ls replay: rejected %s: %s\n", rejection.Heartbeat.Entity, rejection.Reason)
+			}
+			if history != nil {
+				history.Append(accepted)
+			}
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "hackatime-ls replay: sent %d of %d heartbeats: %v\n", sent, len(heartbeats), err)
+			return 1
+		}
+		sent += len(batch)
+		if history != nil {
+			history.Append(batch)
+		}
+	}
+
+	fmt.Printf("Sent %d heartbeats\n", sent)
+	return 0
+}
+
+func unconfirmedHeartbeats(path string, since time.Time) ([]hackatime.Heartbeat, error) {
+	files, err := logFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	logged := make(map[string]hackatime.Heartbeat)
+	confirmed := make(map[string]bool)
+	for _, file := range files {
+		if err := readLoggedEvents(file, func(event loggedEvent) {
+			switch {
+			case event.Heartbeat != nil && (event.Event == "HeartbeatQueued" || event.Event == "HeartbeatFailed"):
+				logged[heartbeatKey(event.Heartbeat.Entity, event.Heartbeat.Time)] = *event.Heartbeat
+			case event.Event == "HeartbeatSent" && event.Result == "ok":
+				confirmed[heartbeatKey(event.Entity, event.Time)] = true
+			}
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, storePath := range []string{config.HistoryFilePath(), config.QueueFilePath()} {
+		if storePath == "" {
+			continue
+		}
+		stored, err := hackatime.NewStore(storePath).Read(nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, hb := range stored {
+			confirmed[heartbeatKey(hb.Entity, hb.Time)] = true
+		}
+	}
+
+	var heartbeats []hackatime.Heartbeat
+	for key, hb := range logged {
+		if !confirmed[key] && hb.Time >= float64(since.Unix()) {
+			heartbeats = append(heartbeats, hb)
+		}
+	}
+	slices.SortFunc(heartbeats, func(a, b hackatime.Heartbeat) int {
+		return cmp.Compare(a.Time, b.Time)
+	})
+	return heartbeats, nil
+}
+
+func logFiles(path string) ([]string, error) {
+	backups, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	var numbered []int
+	for _, backup := range backups {
+		if n, err := strconv.Atoi(strings.TrimPrefix(backup, path+".")); err == nil {
+			numbered = append(numbered, n)
+		}
+	}
+	slices.Sort(numbered)
+
+	var files []string
+	for _, n := range slices.Backward(numbered) {
+		files = append(files, path+"."+strconv.Itoa(n))
+	}
+	return append(files, path), nil
+}
+
+func readLoggedEvents(path string, each func(event loggedEvent)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event loggedEvent
+		if json.Unmarshal(scanner.Bytes(), &event) == nil {
+			each(event)
+		}
+	}
+	return scanner.Err()
+}
+
+func heartbeatKey(entity string, t float64) string {
+	return entity + "@" + strconv.FormatFloat(t, 'f', 3, 64)
+}
@@ -0,0 +1,160 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+type report struct {
+	Start     time.Time
+	End       time.Time
+	Total     time.Duration
+	Projects  []hackatime.SummaryItem
+	Languages []hackatime.SummaryItem
+}
+
+func Report(args []string) int {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	rangeFlag := flags.String("range", "7d", "How far back to report, e.g. 1d, 7d or 4w")
+	local := flags.Bool("local", false, "Use the local heartbeat history instead of the API")
+	markdown := flags.Bool("markdown", false, "Print Markdown tables")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	days, err := parseRange(*rangeFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls report:", err)
+		return 2
+	}
+	end := time.Now()
+	start := startOfDay(end.AddDate(0, 0, -(days - 1)))
+
+	var r *report
+	if *local {
+		r, err = localReport(start, end)
+	} else {
+		r, err = apiReport(start, end)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls report:", err)
+		return 1
+	}
+
+	printReport(os.Stdout, r, *markdown)
+	return 0
+}
+
+func parseRange(value string) (int, error) {
+	if len(value) < 2 {
+		return 0, fmt.Errorf("invalid --range %q, expected something like 7d or 4w", value)
+	}
+
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid --range %q, expected something like 7d or 4w", value)
+	}
+	switch value[len(value)-1] {
+	case 'd':
+		return n, nil
+	case 'w':
+		return n * 7, nil
+	}
+	return 0, fmt.Errorf("invalid --range %q, expected something like 7d or 4w", value)
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func apiReport(start, end time.Time) (*report, error) {
+	client, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	summaries, err := client.Summaries(context.Background(), hackatime.SummariesQuery{Start: start, End: end})
+	if err != nil {
+		return nil, err
+	}
+
+	r := &report{
+		Start: start,
+		End:   end,
+		Total: time.Duration(summaries.CumulativeTotal.TotalSeconds * float64(time.Second)),
+	}
+	r.Projects, r.Languages = summaries.Totals()
+	return r, nil
+}
+
+func localReport(start, end time.Time) (*report, error) {
+	path := config.HistoryFilePath()
+	if path == "" {
+		return nil, errors.New("could not determine home directory")
+	}
+
+	heartbeats, err := hackatime.NewStore(path).Read(func(hb hackatime.Heartbeat) bool {
+		return hb.Time >= float64(start.Unix()) && hb.Time <= float64(end.Unix())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := time.Duration(config.Int("keystroke_timeout", int(hackatime.DefaultKeystrokeTimeout/time.Minute))) * time.Minute
+	durations := hackatime.Durations(heartbeats, timeout)
+
+	r := &report{
+		Start: start,
+		End:   end,
+		Projects: hackatime.DurationItems(hackatime.DurationsBy(durations, func(d hackatime.Duration) string {
+			return orUnknown(d.Project)
+		})),
+		Languages: hackatime.DurationItems(hackatime.DurationsBy(durations, func(d hackatime.Duration) string {
+			return orUnknown(hackatime.WakatimeLanguage(d.Language))
+		})),
+	}
+	for _, d := range durations {
+		r.Total += d.Duration
+	}
+	return r, nil
+}
+
+func orUnknown(name string) string {
+	if name == "" {
+		return "Unknown"
+	}
+	return name
+}
+
+func printReport(w io.Writer, r *report, markdown bool) {
+	fmt.Fprintf(w, "%s to %s: %s\n", r.Start.Format(dateLayout), r.End.Format(dateLayout), hackatime.FormatDuration(r.Total))
+
+	if markdown {
+		printMarkdownTable(w, "Project", r.Projects)
+		printMarkdownTable(w, "Language", r.Languages)
+		return
+	}
+	printItems(w, "Projects", r.Projects)
+	printItems(w, "Languages", r.Languages)
+}
+
+func printMarkdownTable(w io.Writer, title string, items []hackatime.SummaryItem) {
+	if len(items) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n| %s | Time | %% |\n| --- | --- | --- |\n", title)
+	for _, item := range items {
+		fmt.Fprintf(w, "| %s | %s | %.1f%% |\n", strings.ReplaceAll(item.Name, "|", `\|`), item.Text, item.Percent)
+	}
+}
@@ -0,0 +1,63 @@
+package command
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/update"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/version"
+)
+
+const selfUpdateTimeout = 5 * time.Minute
+
+func SelfUpdate(args []string) int {
+	flags := flag.NewFlagSet("self-update", flag.ContinueOnError)
+	check := flags.Bool("check", false, "Only report whether a newer release exists")
+	force := flags.Bool("force", false, "Install the latest release even if it isn't newer")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), selfUpdateTimeout)
+	defer cancel()
+
+	release, err := update.Latest(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls self-update:", err)
+		return 1
+	}
+
+	newer := update.Newer(release.Version(), version.Version)
+	if !newer && !*force {
+		if version.Version == "dev" {
+			fmt.Printf("This is a development build, the latest release is %s. Pass --force to install it.\n", release.Version())
+			return 0
+		}
+		fmt.Printf("%s %s is up to date (latest release is %s)\n", version.Name, version.Version, release.Version())
+		return 0
+	}
+	if *check {
+		fmt.Printf("%s %s is available (you have %s)\n", version.Name, release.Version(), version.Version)
+		return 0
+	}
+
+	executable, err := os.Executable()
+	if err == nil {
+		executable, err = filepath.EvalSymlinks(executable)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls self-update:", err)
+		return 1
+	}
+
+	if err := update.Apply(ctx, release, executable); err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls self-update:", err)
+		return 1
+	}
+	fmt.Printf("Updated %s to %s, restart Zed's language server to use it\n", executable, release.Version())
+	return 0
+}
@@ -0,0 +1,162 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/version"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+const sendTestTimeout = 30 * time.Second
+
+var cliExitCodes = map[int]string{
+	102: "the API could not be reached or returned an error, the heartbeat was saved to wakatime-cli's offline queue",
+	103: "~/.wakatime.cfg could not be parsed",
+	104: "the API key was rejected",
+	110: "~/.wakatime.cfg could not be read",
+	111: "~/.wakatime.cfg could not be written",
+	112: "wakatime-cli is backing off after earlier failures, try again in a few minutes",
+}
+
+func SendTest(args []string) int {
+	flags := flag.NewFlagSet("send-test", flag.ContinueOnError)
+	cliFlag := flags.String("wakatime-cli", "", "Path to wakatime-cli (default: wakatime-cli on PATH or in ~/.wakatime)")
+	useAPI := flags.Bool("api", false, "Send the heartbeat straight to the API instead of through wakatime-cli")
+	project := flags.String("project", "", "Project name (default: the file's directory)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: hackatime-ls send-test [--api] [--project name] <file>")
+		return 2
+	}
+
+	hb, err := testHeartbeat(flags.Arg(0), *project)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls send-test:", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTestTimeout)
+	defer cancel()
+
+	if *useAPI {
+		err = sendTestAPI(ctx, hb)
+	} else {
+		err = sendTestCLI(ctx, hb, *cliFlag)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls send-test:", err)
+		return 1
+	}
+	fmt.Println("OK, the heartbeat should show up on your dashboard within a minute")
+	return 0
+}
+
+func testHeartbeat(file, project string) (hackatime.Heartbeat, error) {
+	path, err := filepath.Abs(config.ExpandHome(file))
+	if err != nil {
+		return hackatime.Heartbeat{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return hackatime.Heartbeat{}, err
+	}
+	if project == "" {
+		project = filepath.Base(filepath.Dir(path))
+	}
+
+	return hackatime.Heartbeat{
+		Entity:           path,
+		EntityType:       "file",
+		Category:         "coding",
+		Plugin:           version.Plugin("", ""),
+		Time:             float64(time.Now().UnixMilli()) / 1000.0,
+		LineNumber:       1,
+		CursorPos:        1,
+		Lines:            bytes.Count(data, []byte("\n")) + 1,
+		AlternateProject: project,
+		ProjectFolder:    filepath.Dir(path),
+		IsWrite:          true,
+	}, nil
+}
+
+func sendTestCLI(ctx context.Context, hb hackatime.Heartbeat, cliFlag string) error {
+	cliPath, err := findCLI(cliFlag)
+	if err != nil {
+		return err
+	}
+
+	args := hackatime.CLIArgs(hb, cliOptions())
+	fmt.Println("Running:")
+	fmt.Println(" ", shellQuote(append([]string{cliPath}, hackatime.RedactArgs(args)...)))
+
+	output, err := exec.CommandContext(ctx, cliPath, args...).CombinedOutput()
+	if text := strings.TrimSpace(string(output)); text != "" {
+		fmt.Println("Output:")
+		fmt.Println(" ", strings.ReplaceAll(text, "\n", "\n  "))
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		fmt.Println("wakatime-cli exited with 0")
+		return nil
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("wakatime-cli timed out after %s", sendTestTimeout)
+	case errors.As(err, &exitErr):
+		if reason, known := cliExitCodes[exitErr.ExitCode()]; known {
+			return fmt.Errorf("wakatime-cli exited with %d: %s (details in %s)", exitErr.ExitCode(), reason, config.LogFilePath())
+		}
+		return fmt.Errorf("wakatime-cli exited with %d (details in %s)", exitErr.ExitCode(), config.LogFilePath())
+	default:
+		return err
+	}
+}
+
+func sendTestAPI(ctx context.Context, hb hackatime.Heartbeat) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	client.UserAgent = hb.Plugin
+	body := hackatime.ToAPIHeartbeat(hb)
+	fmt.Println("Request:")
+	fmt.Printf("  POST %s/users/current/heartbeats\n", client.ApiUrl)
+	fmt.Printf("  User-Agent: %s\n", client.UserAgent)
+	request, _ := json.MarshalIndent(body, "  ", "  ")
+	fmt.Println(" ", string(request))
+
+	response, err := client.SendHeartbeat(ctx, body)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Response:")
+	if indented, err := json.MarshalIndent(response, "  ", "  "); err == nil {
+		fmt.Println(" ", string(indented))
+	}
+	return nil
+}
+
+func shellQuote(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\"'$\\") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,131 @@
+package command
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/instance"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+type serverStatus struct {
+	Running       bool      `json:"running"`
+	Pid           int       `json:"pid"`
+	StartedAt     time.Time `json:"started_at"`
+	Project       string    `json:"project"`
+	Queued        int       `json:"queued"`
+	Paused        bool      `json:"paused"`
+	Today         string    `json:"today"`
+	LocalToday    string    `json:"local_today"`
+	LastHeartbeat *struct {
+		Entity  string    `json:"entity"`
+		Project string    `json:"project"`
+		Time    time.Time `json:"time"`
+	} `json:"last_heartbeat"`
+	LastSent    *time.Time `json:"last_sent"`
+	LastError   string     `json:"last_error"`
+	LastErrorAt *time.Time `json:"last_error_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func Status(args []string) int {
+	flags := flag.NewFlagSet("status", flag.ContinueOnError)
+	file := flags.String("file", "", "Status file to read (default ~/.wakatime/hackatime-zed-status.json)")
+	asJSON := flags.Bool("json", false, "Print the status file as-is")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	path := *file
+	if path == "" {
+		path = config.Value("status_file")
+	}
+	if path == "" {
+		path = config.StatusFilePath()
+	}
+
+	data, err := os.ReadFile(config.ExpandHome(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Println("hackatime-ls has not run yet (no status file at " + path + ")")
+			return 1
+		}
+		fmt.Fprintln(os.Stderr, "hackatime-ls status:", err)
+		return 1
+	}
+
+	var status serverStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls status:", err)
+		return 1
+	}
+	running := status.Running && instance.Alive(status.Pid)
+
+	if *asJSON {
+		os.Stdout.Write(data)
+		fmt.Println()
+	} else {
+		printStatus(os.Stdout, status, running)
+	}
+	if !running {
+		return 1
+	}
+	return 0
+}
+
+func printStatus(w io.Writer, status serverStatus, running bool) {
+	switch {
+	case running && !status.StartedAt.IsZero():
+		fmt.Fprintf(w, "hackatime-ls is running (pid %d, up %s)\n", status.Pid, hackatime.FormatDuration(time.Since(status.StartedAt)))
+	case running:
+		fmt.Fprintf(w, "hackatime-ls is running (pid %d)\n", status.Pid)
+	case status.Running:
+		fmt.Fprintf(w, "hackatime-ls is not running, pid %d exited without cleaning up (last update %s)\n", status.Pid, ago(status.UpdatedAt))
+	default:
+		fmt.Fprintf(w, "hackatime-ls is not running (stopped %s)\n", ago(status.UpdatedAt))
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	defer table.Flush()
+
+	if status.Project != "" {
+		fmt.Fprintf(table, "Project:\t%s\n", status.Project)
+	}
+	queued := fmt.Sprint(status.Queued)
+	if status.Paused {
+		queued += " (paused)"
+	}
+	fmt.Fprintf(table, "Queued:\t%s\n", queued)
+
+	if hb := status.LastHeartbeat; hb != nil {
+		fmt.Fprintf(table, "Last heartbeat:\t%s, %s (%s)\n", ago(hb.Time), hb.Entity, orUnknown(hb.Project))
+	}
+	lastErrorIsNewer := status.LastErrorAt != nil && (status.LastSent == nil || status.LastErrorAt.After(*status.LastSent))
+	switch {
+	case lastErrorIsNewer:
+		fmt.Fprintf(table, "Last send:\tfailed %s: %s\n", ago(*status.LastErrorAt), status.LastError)
+	case status.LastSent != nil:
+		fmt.Fprintf(table, "Last send:\tok, %s\n", ago(*status.LastSent))
+	default:
+		fmt.Fprintln(table, "Last send:\tnothing sent yet")
+	}
+
+	if status.Today != "" {
+		fmt.Fprintf(table, "Today:\t%s\n", status.Today)
+	} else if status.LocalToday != "" {
+		fmt.Fprintf(table, "Today:\t%s (local)\n", status.LocalToday)
+	}
+}
+
+func ago(t time.Time) string {
+	if time.Since(t) < time.Minute {
+		return "just now"
+	}
+	return hackatime.FormatDuration(time.Since(t)) + " ago"
+}
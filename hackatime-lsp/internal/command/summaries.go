@@ -0,0 +1,128 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/version"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+const dateLayout = "2006-01-02"
+
+func Summaries(args []string) int {
+	flags := flag.NewFlagSet("summaries", flag.ContinueOnError)
+	days := flags.Int("days", 7, "Number of days up to --end to include")
+	start := flags.String("start", "", "First day to include (YYYY-MM-DD), overrides --days")
+	end := flags.String("end", "", "Last day to include (YYYY-MM-DD, default today)")
+	project := flags.String("project", "", "Only include this project")
+	asJSON := flags.Bool("json", false, "Print the raw API response")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	query, err := summariesQuery(*start, *end, *days, *project)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls summaries:", err)
+		return 2
+	}
+
+	client, err := newClient()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls summaries:", err)
+		return 1
+	}
+
+	summaries, err := client.Summaries(context.Background(), query)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls summaries:", err)
+		return 1
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(summaries)
+		return 0
+	}
+	printSummaries(os.Stdout, query, summaries)
+	return 0
+}
+
+func summariesQuery(start, end string, days int, project string) (hackatime.SummariesQuery, error) {
+	query := hackatime.SummariesQuery{End: time.Now(), Project: project}
+	if end != "" {
+		parsed, err := hackatime.ParseDate(end)
+		if err != nil {
+			return query, fmt.Errorf("--end: %w", err)
+		}
+		query.End = parsed
+	}
+
+	if start != "" {
+		parsed, err := hackatime.ParseDate(start)
+		if err != nil {
+			return query, fmt.Errorf("--start: %w", err)
+		}
+		query.Start = parsed
+		return query, nil
+	}
+
+	if days < 1 {
+		return query, errors.New("--days must be at least 1")
+	}
+	query.Start = query.End.AddDate(0, 0, -(days - 1))
+	return query, nil
+}
+
+func newClient() (*hackatime.Client, error) {
+	apiKey := config.ApiKey()
+	if apiKey == "" {
+		return nil, errors.New("no api_key in ~/.wakatime.cfg")
+	}
+	return newClientFor(config.ApiUrl(), apiKey)
+}
+
+func newClientFor(apiUrl, apiKey string) (*hackatime.Client, error) {
+	client := hackatime.NewClient(apiUrl, apiKey)
+	client.UserAgent = version.UserAgent()
+	httpClient, err := hackatime.NewHTTPClient(config.HTTPOptions(config.Settings{}))
+	if err != nil {
+		return nil, err
+	}
+	client.HTTPClient = httpClient
+	return client, nil
+}
+
+func printSummaries(w io.Writer, query hackatime.SummariesQuery, summaries *hackatime.Summaries) {
+	total := summaries.CumulativeTotal.Text
+	if total == "" {
+		total = formatSeconds(summaries.CumulativeTotal.TotalSeconds)
+	}
+	fmt.Fprintf(w, "%s to %s: %s\n", query.Start.Format(dateLayout), query.End.Format(dateLayout), total)
+
+	projects, languages := summaries.Totals()
+	printItems(w, "Projects", projects)
+	printItems(w, "Languages", languages)
+}
+
+func printItems(w io.Writer, title string, items []hackatime.SummaryItem) {
+	if len(items) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n%s\n", title)
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, item := range items {
+		fmt.Fprintf(table, "  %s\t%s\t%.1f%%\n", item.Name, item.Text, item.Percent)
+	}
+	table.Flush()
+}
@@ -0,0 +1,46 @@
+package command
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+func Today(args []string) int {
+	flags := flag.NewFlagSet("today", flag.ContinueOnError)
+	cliFlag := flags.String("wakatime-cli", "", "Path to wakatime-cli (default: wakatime-cli on PATH or in ~/.wakatime)")
+	useAPI := flags.Bool("api", false, "Ask the API directly instead of wakatime-cli")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if !*useAPI {
+		if cliPath, err := findCLI(*cliFlag); err == nil {
+			if text, err := hackatime.Today(hackatime.ExecRunner{}, cliPath, cliOptions()); err == nil && text != "" {
+				fmt.Println(text)
+				return 0
+			}
+		} else if *cliFlag != "" {
+			fmt.Fprintln(os.Stderr, "hackatime-ls today:", err)
+			return 1
+		}
+	}
+
+	client, err := newClient()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls today:", err)
+		return 1
+	}
+
+	summaries, err := client.Summaries(context.Background(), hackatime.SummariesQuery{})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hackatime-ls today:", err)
+		return 1
+	}
+	fmt.Println(hackatime.FormatDuration(time.Duration(summaries.CumulativeTotal.TotalSeconds * float64(time.Second))))
+	return 0
+}
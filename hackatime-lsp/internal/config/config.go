@@ -0,0 +1,625 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+const (
+	vaultCmdTimeoutSec = 10
+	vaultRetryInterval = time.Minute
+)
+
+var (
+	vaultApiKey   string
+	vaultCmdUsed  string
+	vaultErr      error
+	vaultRetryAt  time.Time
+	vaultKeyMutex sync.Mutex
+)
+
+type Sections map[string]map[string]string
+
+type parsedFile struct {
+	path     string
+	modTime  time.Time
+	size     int64
+	sections Sections
+}
+
+var (
+	parsed      parsedFile
+	parsedMutex sync.Mutex
+)
+
+func Read() Sections {
+	configFile := FilePath()
+	if configFile == "" {
+		return Sections{}
+	}
+	info, err := os.Stat(configFile)
+	if err != nil {
+		return Sections{}
+	}
+
+	parsedMutex.Lock()
+	defer parsedMutex.Unlock()
+
+	if parsed.sections != nil && parsed.path == configFile && parsed.modTime.Equal(info.ModTime()) && parsed.size == info.Size() {
+		return parsed.sections
+	}
+
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return Sections{}
+	}
+	parsed = parsedFile{
+		path:     configFile,
+		modTime:  info.ModTime(),
+		size:     info.Size(),
+		sections: parse(string(data)),
+	}
+	return parsed.sections
+}
+
+func parse(data string) Sections {
+	sections := Sections{}
+	section := ""
+	lastKey := ""
+	for _, rawLine := range strings.Split(data, "\n") {
+		line := strings.TrimSpace(rawLine)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			lastKey = ""
+			continue
+		}
+
+		if sections[section] == nil {
+			sections[section] = make(map[string]string)
+		}
+
+		if lastKey != "" && (strings.HasPrefix(rawLine, " ") || strings.HasPrefix(rawLine, "\t")) {
+			if sections[section][lastKey] == "" {
+				sections[section][lastKey] = line
+			} else {
+				sections[section][lastKey] += "\n" + line
+			}
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		lastKey = strings.TrimSpace(parts[0])
+		sections[section][lastKey] = strings.TrimSpace(parts[1])
+	}
+
+	return sections
+}
+
+func Value(key string) string {
+	sections := Read()
+
+	if value := sections["hackatime"][key]; value != "" {
+		return value
+	}
+	if value := sections["settings"][key]; value != "" {
+		return value
+	}
+	return sections[""][key]
+}
+
+func Int(key string, fallback int) int {
+	value := Value(key)
+	if value == "" {
+		return fallback
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return fallback
+	}
+	return n
+}
+
+func ApiKey() string {
+	if apiKey := Value("api_key"); apiKey != "" {
+		return apiKey
+	}
+
+	vaultCmd := Value("api_key_vault_cmd")
+	if vaultCmd == "" {
+		return ""
+	}
+
+	vaultKeyMutex.Lock()
+	defer vaultKeyMutex.Unlock()
+
+	if vaultCmdUsed == vaultCmd && (vaultApiKey != "" || time.Now().Before(vaultRetryAt)) {
+		return vaultApiKey
+	}
+
+	apiKey, err := runVaultCmd(vaultCmd)
+	if err != nil {
+		if vaultErr == nil || vaultCmdUsed != vaultCmd {
+			slog.Warn("ApiKeyVaultCmdFailed", "retry_in", vaultRetryInterval.String(), "error", err)
+		}
+		vaultApiKey = ""
+		vaultCmdUsed = vaultCmd
+		vaultErr = err
+		vaultRetryAt = time.Now().Add(vaultRetryInterval)
+		return ""
+	}
+
+	vaultApiKey = apiKey
+	vaultCmdUsed = vaultCmd
+	vaultErr = nil
+	return apiKey
+}
+
+func runVaultCmd(vaultCmd string) (string, error) {
+	args := splitCommandLine(vaultCmd)
+	if len(args) == 0 {
+		return "", errors.New("api_key_vault_cmd is empty")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), vaultCmdTimeoutSec*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, args[0], args[1:]...).Output()
+	if err != nil {
+		return "", err
+	}
+
+	apiKey := strings.TrimSpace(string(out))
+	if apiKey == "" {
+		return "", errors.New("api_key_vault_cmd returned an empty key")
+	}
+	return apiKey, nil
+}
+
+func splitCommandLine(cmd string) []string {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	inArg := false
+
+	for _, ch := range cmd {
+		switch {
+		case quote != 0 && ch == quote:
+			quote = 0
+		case quote == 0 && (ch == '"' || ch == '\''):
+			quote = ch
+			inArg = true
+		case quote == 0 && (ch == ' ' || ch == '\t'):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(ch)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args
+}
+
+func ApiUrl() string {
+	if apiUrl := Value("api_url"); apiUrl != "" {
+		return apiUrl
+	}
+	return hackatime.DefaultApiUrl
+}
+
+type Backend struct {
+	Name   string
+	ApiUrl string
+	ApiKey string
+}
+
+func Backends() []Backend {
+	var backends []Backend
+	for section, values := range Read() {
+		name, ok := strings.CutPrefix(section, "backend.")
+		if !ok || name == "" {
+			continue
+		}
+		backends = append(backends, Backend{Name: name, ApiUrl: values["api_url"], ApiKey: values["api_key"]})
+	}
+	slices.SortFunc(backends, func(a, b Backend) int { return strings.Compare(a.Name, b.Name) })
+	return backends
+}
+
+type Account struct {
+	Backend
+	Projects []string
+	Paths    []string
+}
+
+func Accounts() []Account {
+	var accounts []Account
+	for section, values := range Read() {
+		name, ok := strings.CutPrefix(section, "account.")
+		if !ok || name == "" {
+			continue
+		}
+		account := Account{
+			Backend:  Backend{Name: name, ApiUrl: values["api_url"], ApiKey: values["api_key"]},
+			Projects: splitList(values["projects"]),
+		}
+		for _, path := range splitList(values["paths"]) {
+			account.Paths = append(account.Paths, filepath.Clean(ExpandHome(path)))
+		}
+		accounts = append(accounts, account)
+	}
+	slices.SortFunc(accounts, func(a, b Account) int { return strings.Compare(a.Name, b.Name) })
+	return accounts
+}
+
+func FindAccount(name string) (Account, bool) {
+	for _, account := range Accounts() {
+		if account.Name == name {
+			return account, true
+		}
+	}
+	return Account{}, false
+}
+
+func (a Account) Destination() Backend {
+	destination := a.Backend
+	if destination.ApiUrl == "" {
+		destination.ApiUrl = ApiUrl()
+	}
+	if destination.ApiKey == "" {
+		destination.ApiKey = ApiKey()
+	}
+	return destination
+}
+
+func AccountFor(project, entity string) string {
+	accounts := Accounts()
+	for _, account := range accounts {
+		if project != "" && slices.Contains(account.Projects, project) {
+			return account.Name
+		}
+	}
+
+	match, matchLen := "", 0
+	for _, account := range accounts {
+		for _, path := range account.Paths {
+			if (entity == path || strings.HasPrefix(entity, path+string(filepath.Separator))) && len(path) > matchLen {
+				match, matchLen = account.Name, len(path)
+			}
+		}
+	}
+	return match
+}
+
+type Remote struct {
+	Name    string
+	Host    string
+	Path    string
+	Local   string
+	Project string
+}
+
+func Remotes() []Remote {
+	var remotes []Remote
+	for section, values := range Read() {
+		name, ok := strings.CutPrefix(section, "remote.")
+		if !ok || name == "" || values["path"] == "" {
+			continue
+		}
+		remote := Remote{
+			Name:    name,
+			Host:    strings.ToLower(values["host"]),
+			Path:    strings.TrimSuffix(filepath.ToSlash(values["path"]), "/"),
+			Project: values["project"],
+		}
+		if local := values["local"]; local != "" {
+			remote.Local = filepath.Clean(ExpandHome(local))
+		}
+		remotes = append(remotes, remote)
+	}
+	slices.SortFunc(remotes, func(a, b Remote) int { return strings.Compare(a.Name, b.Name) })
+	return remotes
+}
+
+func RemoteFor(host, path string) (Remote, string, bool) {
+	var match Remote
+	rel, found := "", false
+	for _, remote := range Remotes() {
+		if remote.Host != "" && !strings.EqualFold(remote.Host, host) {
+			continue
+		}
+		if path != remote.Path && !strings.HasPrefix(path, remote.Path+"/") {
+			continue
+		}
+		if !found || len(remote.Path) > len(match.Path) {
+			match, rel, found = remote, strings.TrimPrefix(path[len(remote.Path):], "/"), true
+		}
+	}
+	return match, rel, found
+}
+
+func (r Remote) ProjectName() string {
+	switch {
+	case r.Project != "":
+		return r.Project
+	case r.Local != "":
+		return filepath.Base(r.Local)
+	default:
+		return path.Base(r.Path)
+	}
+}
+
+type PathMapping struct {
+	From string
+	To   string
+}
+
+func PathMappings() []PathMapping {
+	var mappings []PathMapping
+	for from, to := range Read()["path_map"] {
+		if from == "" || to == "" {
+			continue
+		}
+		mappings = append(mappings, PathMapping{From: filepath.Clean(from), To: filepath.Clean(to)})
+	}
+	slices.SortFunc(mappings, func(a, b PathMapping) int { return len(b.From) - len(a.From) })
+	return mappings
+}
+
+func MapPath(path string) string {
+	if path == "" {
+		return path
+	}
+	for _, mapping := range PathMappings() {
+		rest, ok := strings.CutPrefix(path, mapping.From)
+		if !ok || (rest != "" && !strings.HasPrefix(rest, string(filepath.Separator))) {
+			continue
+		}
+		if strings.Contains(mapping.To, `\`) && !strings.Contains(mapping.To, "/") {
+			rest = strings.ReplaceAll(rest, "/", `\`)
+		}
+		return mapping.To + rest
+	}
+	return path
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func HTTPOptions(settings Settings) hackatime.HTTPOptions {
+	options := hackatime.HTTPOptions{
+		Proxy:          settings.String("proxy"),
+		NoSSLVerify:    settings.Bool("no_ssl_verify", false),
+		ConnectTimeout: time.Duration(settings.Int("api_connect_timeout", 0)) * time.Second,
+		ReadTimeout:    time.Duration(settings.Int("api_read_timeout", 0)) * time.Second,
+	}
+	if certsFile := settings.String("ssl_certs_file"); certsFile != "" {
+		options.SSLCertsFile = ExpandHome(certsFile)
+	}
+	return options
+}
+
+func Set(section, key, value string) error {
+	configFile := FilePath()
+	if configFile == "" {
+		return errors.New("could not determine home directory")
+	}
+
+	data, err := os.ReadFile(configFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var lines []string
+	if len(data) > 0 {
+		lines = strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	}
+
+	entry := key + " = " + value
+	current := ""
+	sectionEnd := -1
+	var out []string
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			current = strings.ToLower(strings.TrimSpace(trimmed[1 : len(trimmed)-1]))
+			out = append(out, line)
+			if current == section {
+				sectionEnd = len(out)
+			}
+			continue
+		}
+
+		parts := strings.SplitN(trimmed, "=", 2)
+		if current == section && entry != "" && len(parts) == 2 && strings.TrimSpace(parts[0]) == key {
+			out = append(out, entry)
+			entry = ""
+			for i+1 < len(lines) && (strings.HasPrefix(lines[i+1], " ") || strings.HasPrefix(lines[i+1], "\t")) && strings.TrimSpace(lines[i+1]) != "" {
+				i++
+			}
+			sectionEnd = len(out)
+			continue
+		}
+
+		out = append(out, line)
+		if current == section && trimmed != "" {
+			sectionEnd = len(out)
+		}
+	}
+
+	if entry != "" {
+		if sectionEnd >= 0 {
+			out = append(out[:sectionEnd], append([]string{entry}, out[sectionEnd:]...)...)
+		} else {
+			if len(out) > 0 {
+				out = append(out, "")
+			}
+			out = append(out, "["+section+"]", entry)
+		}
+	}
+
+	tmpFile := configFile + ".tmp"
+	if err := os.WriteFile(tmpFile, []byte(strings.Join(out, "\n")+"\n"), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpFile, configFile); err != nil {
+		return err
+	}
+
+	parsedMutex.Lock()
+	parsed = parsedFile{}
+	parsedMutex.Unlock()
+	return nil
+}
+
+func EnsureFile() error {
+	configFile := FilePath()
+	if configFile == "" {
+		return errors.New("could not determine home directory")
+	}
+
+	file, err := os.OpenFile(configFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteString("[hackatime]\n")
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func FilePath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".wakatime.cfg")
+}
+
+func LogFilePath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".wakatime", "wakatime.log")
+}
+
+func FileTimesPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".wakatime", "hackatime-zed-files.json")
+}
+
+func StatusFilePath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".wakatime", "hackatime-zed-status.json")
+}
+
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(homeDir, path[1:])
+}
+
+func QueueFilePath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".wakatime", "hackatime-zed-queue.jsonl")
+}
+
+func BackendQueueFilePath(name string) string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	safe := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, name)
+	return filepath.Join(homeDir, ".wakatime", "hackatime-zed-queue-"+safe+".jsonl")
+}
+
+func DaemonSocketPath() string {
+	if runtime.GOOS == "windows" {
+		return `\\.\pipe\hackatime-zed-` + os.Getenv("USERNAME")
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".wakatime", "hackatime-zed.sock")
+}
+
+func InstancesDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".wakatime", "hackatime-zed-instances")
+}
+
+func HistoryFilePath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".wakatime", "hackatime-zed-history.jsonl")
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Settings struct {
+	InitOptions map[string]interface{}
+}
+
+func (s Settings) InitOption(key string) string {
+	if value, ok := s.InitOptions[key]; ok && value != nil {
+		return fmt.Sprint(value)
+	}
+	return ""
+}
+
+func (s Settings) String(key string) string {
+	if value := s.InitOption(key); value != "" {
+		return value
+	}
+	return Value(key)
+}
+
+func (s Settings) Bool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(s.String(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func (s Settings) Int(key string, fallback int) int {
+	n, err := strconv.Atoi(s.String(key))
+	if err != nil || n <= 0 {
+		return fallback
+	}
+	return n
+}
+
+func (s Settings) List(key string) []string {
+	var raw []string
+	if values, ok := s.InitOptions[key].([]interface{}); ok {
+		for _, value := range values {
+			raw = append(raw, fmt.Sprint(value))
+		}
+	} else {
+		raw = strings.FieldsFunc(s.String(key), func(r rune) bool {
+			return r == ',' || r == '\n'
+		})
+	}
+
+	var list []string
+	for _, value := range raw {
+		if value = strings.TrimSpace(value); value != "" {
+			list = append(list, value)
+		}
+	}
+	return list
+}
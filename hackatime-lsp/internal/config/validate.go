@@ -0,0 +1,34 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+var apiKeyPattern = regexp.MustCompile(`(?i)^(waka_)?[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+func ValidateApiKey(apiKey string) error {
+	if apiKey == "" {
+		return fmt.Errorf("no API key found, set api_key in %s", FilePath())
+	}
+	if !apiKeyPattern.MatchString(apiKey) {
+		return errors.New("API key doesn't look like a valid key (expected a UUID)")
+	}
+	return nil
+}
+
+func ValidateApiUrl(apiUrl string) error {
+	u, err := url.Parse(apiUrl)
+	if err != nil {
+		return fmt.Errorf("API URL %q can't be parsed: %v", apiUrl, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("API URL %q must start with http:// or https://", apiUrl)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("API URL %q has no host", apiUrl)
+	}
+	return nil
+}
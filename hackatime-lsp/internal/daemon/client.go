@@ -0,0 +1,139 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+type Client struct {
+	mutex     sync.Mutex
+	path      string
+	workspace string
+	conn      net.Conn
+	encoder   *json.Encoder
+	decoder   *json.Decoder
+}
+
+func Dial(path, workspace string) (*Client, error) {
+	c := &Client{path: path, workspace: workspace}
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) connect() error {
+	conn, err := dial(c.path)
+	if err != nil {
+		return err
+	}
+
+	encoder, decoder := json.NewEncoder(conn), json.NewDecoder(conn)
+	hello := currentHello()
+	hello.Workspace = c.workspace
+	var resp response
+	if err := roundTrip(conn, encoder, decoder, request{Hello: hello}, &resp); err != nil {
+		conn.Close()
+		return err
+	}
+	if resp.Hello == nil {
+		conn.Close()
+		return errors.New("daemon did not answer the handshake")
+	}
+	if !currentHello().compatible(*resp.Hello) {
+		conn.Close()
+		return &VersionMismatchError{Daemon: *resp.Hello}
+	}
+
+	c.conn = conn
+	c.encoder = encoder
+	c.decoder = decoder
+	return nil
+}
+
+func roundTrip(conn net.Conn, encoder *json.Encoder, decoder *json.Decoder, req request, resp *response) error {
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	if err := encoder.Encode(req); err != nil {
+		return err
+	}
+	return decoder.Decode(resp)
+}
+
+func (c *Client) Send(hb hackatime.Heartbeat) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.conn != nil {
+		if err := c.encoder.Encode(request{Heartbeat: &hb}); err == nil {
+			return nil
+		}
+		c.closeLocked()
+	}
+
+	if err := c.connect(); err != nil {
+		return err
+	}
+	if err := c.encoder.Encode(request{Heartbeat: &hb}); err != nil {
+		c.closeLocked()
+		return err
+	}
+	return nil
+}
+
+func (c *Client) ReportMetrics(counts Counts) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.conn == nil {
+		return errors.New("not connected to the daemon")
+	}
+	if err := c.encoder.Encode(request{Metrics: &counts}); err != nil {
+		c.closeLocked()
+		return err
+	}
+	return nil
+}
+
+func (c *Client) Status() (WorkspaceStatus, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.conn == nil {
+		if err := c.connect(); err != nil {
+			return WorkspaceStatus{}, err
+		}
+	}
+
+	var resp response
+	if err := roundTrip(c.conn, c.encoder, c.decoder, request{Status: true}, &resp); err != nil {
+		c.closeLocked()
+		return WorkspaceStatus{}, err
+	}
+	if resp.Status == nil {
+		return WorkspaceStatus{}, errors.New("daemon did not answer the status request")
+	}
+	return *resp.Status, nil
+}
+
+func (c *Client) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	return c.closeLocked()
+}
+
+func (c *Client) closeLocked() error {
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
@@ -0,0 +1,370 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/backend"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/pipe"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/version"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+const (
+	dialTimeout       = time.Second
+	idleCheckInterval = time.Minute
+	handoverRetryWait = 50 * time.Millisecond
+	handoverTimeout   = time.Minute
+)
+
+type Daemon struct {
+	queue    *hackatime.Queue
+	backends backend.Backends
+
+	mutex       sync.Mutex
+	sessions    map[*session]bool
+	lastSeen    time.Time
+	nextSession int
+	recent      map[string]recentHeartbeat
+	lastPrune   time.Time
+	startedAt   time.Time
+	stats       stats
+	listener    net.Listener
+	conns       map[net.Conn]bool
+	workspaces  map[string]*workspaceActivity
+	handedOver  bool
+	handovers   sync.WaitGroup
+}
+
+type recentHeartbeat struct {
+	session int
+	time    time.Time
+}
+
+type session struct {
+	id          int
+	workspace   string
+	connectedAt time.Time
+	throttle    *hackatime.Throttle
+	received    int
+	metrics     *Counts
+}
+
+func New(queue *hackatime.Queue, backends backend.Backends) *Daemon {
+	return &Daemon{
+		queue:      queue,
+		backends:   backends,
+		lastSeen:   time.Now(),
+		recent:     make(map[string]recentHeartbeat),
+		startedAt:  time.Now(),
+		conns:      make(map[net.Conn]bool),
+		sessions:   make(map[*session]bool),
+		workspaces: make(map[string]*workspaceActivity),
+	}
+}
+
+func Listen(path string) (net.Listener, error) {
+	if health, err := Ping(path); err == nil {
+		if health.Version == version.Version && health.Protocol == ProtocolVersion {
+			return nil, fmt.Errorf("a daemon is already listening on %s", path)
+		}
+		handover, err := requestHandover(path)
+		if err != nil {
+			return nil, fmt.Errorf("taking over from daemon %s (pid %d): %w", health.Version, health.Pid, err)
+		}
+		slog.Info("DaemonHandover", "from_version", health.Version, "from_pid", health.Pid, "queued", handover.Queued, "error", handover.Error)
+	} else if conn, err := dial(path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("a daemon is already listening on %s but does not answer", path)
+	}
+	if pipe.IsPath(path) {
+		return listenPipe(path)
+	}
+	os.Remove(path)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	os.Chmod(path, 0600)
+	return listener, nil
+}
+
+func listenPipe(path string) (net.Listener, error) {
+	deadline := time.Now().Add(dialTimeout)
+	for {
+		listener, err := pipe.Listen(path)
+		if err == nil || time.Now().After(deadline) {
+			return listener, err
+		}
+		time.Sleep(handoverRetryWait)
+	}
+}
+
+func (d *Daemon) Serve(listener net.Listener, idleTimeout time.Duration) error {
+	d.mutex.Lock()
+	d.listener = listener
+	d.mutex.Unlock()
+
+	if idleTimeout > 0 {
+		go d.closeWhenIdle(listener, idleTimeout)
+	}
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				d.handovers.Wait()
+				return nil
+			}
+			return err
+		}
+		go d.handle(conn)
+	}
+}
+
+func (d *Daemon) add(session *session, hb hackatime.Heartbeat) bool {
+	d.count(func(stats *stats) {
+		stats.received++
+		session.received++
+	})
+	if !session.throttle.Allow(hb) {
+		slog.Debug("DaemonThrottled", "entity", hb.Entity, "project", hb.AlternateProject)
+		d.count(func(stats *stats) { stats.throttled++ })
+		return false
+	}
+	if d.sentByOtherSession(session, hb) {
+		slog.Debug("DaemonDeduped", "entity", hb.Entity, "project", hb.AlternateProject)
+		d.count(func(stats *stats) { stats.deduped++ })
+		return false
+	}
+	d.queue.Add(hb)
+	d.backends.Add(hb)
+	d.recordWorkspace(session, hb)
+	return true
+}
+
+func (d *Daemon) sentByOtherSession(session *session, hb hackatime.Heartbeat) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	now := time.Now()
+	interval := session.throttle.Interval()
+	if now.Sub(d.lastPrune) >= interval {
+		for entity, recent := range d.recent {
+			if now.Sub(recent.time) >= interval {
+				delete(d.recent, entity)
+			}
+		}
+		d.lastPrune = now
+	}
+
+	recent, exists := d.recent[hb.Entity]
+	if exists && !hb.IsWrite && recent.session != session.id && now.Sub(recent.time) < interval {
+		return true
+	}
+	d.recent[hb.Entity] = recentHeartbeat{session: session.id, time: now}
+	return false
+}
+
+func (d *Daemon) handle(conn net.Conn) {
+	defer conn.Close()
+	if !d.track(conn) {
+		return
+	}
+	defer d.untrack(conn)
+
+	var session *session
+	defer func() {
+		if session != nil {
+			d.disconnect(session)
+		}
+	}()
+
+	decoder := json.NewDecoder(conn)
+	encoder := json.NewEncoder(conn)
+	for {
+		var req request
+		if err := decoder.Decode(&req); err != nil {
+			return
+		}
+
+		switch {
+		case req.Hello != nil:
+			if !currentHello().compatible(*req.Hello) {
+				slog.Info("DaemonVersionMismatch", "client_version", req.Hello.Version, "client_protocol", req.Hello.Protocol, "client_pid", req.Hello.Pid)
+			} else if session == nil {
+				session = d.connect(req.Hello)
+			}
+			if err := encoder.Encode(response{Hello: currentHello()}); err != nil {
+				return
+			}
+		case req.Heartbeat != nil:
+			if session == nil {
+				session = d.connect(nil)
+			}
+			d.add(session, *req.Heartbeat)
+		case req.Ping:
+			health := d.Health()
+			if err := encoder.Encode(response{Health: &health}); err != nil {
+				return
+			}
+		case req.Metrics != nil:
+			if session != nil {
+				d.recordMetrics(session, *req.Metrics)
+			}
+		case req.Status:
+			status := d.workspaceStatus(session)
+			if err := encoder.Encode(response{Status: &status}); err != nil {
+				return
+			}
+		case req.Handover:
+			d.handOver(conn, encoder)
+			return
+		}
+	}
+}
+
+func (d *Daemon) track(conn net.Conn) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.handedOver {
+		return false
+	}
+	d.conns[conn] = true
+	return true
+}
+
+func (d *Daemon) untrack(conn net.Conn) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	delete(d.conns, conn)
+}
+
+func (d *Daemon) handOver(requester net.Conn, encoder *json.Encoder) {
+	d.mutex.Lock()
+	d.handedOver = true
+	d.handovers.Add(1)
+	defer d.handovers.Done()
+	listener := d.listener
+	var conns []net.Conn
+	for conn := range d.conns {
+		if conn != requester {
+			conns = append(conns, conn)
+		}
+	}
+	d.mutex.Unlock()
+
+	if listener != nil {
+		listener.Close()
+	}
+	for _, conn := range conns {
+		conn.Close()
+	}
+
+	queued, err := d.queue.Persist()
+	slog.Info("DaemonHandedOver", "queued", queued, "backends_queued", d.backends.Persist(), "error", err)
+	handover := &Handover{Queued: queued}
+	if err != nil {
+		handover.Error = err.Error()
+	}
+	encoder.Encode(response{Handover: handover})
+}
+
+func (d *Daemon) HandedOver() bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.handedOver
+}
+
+func (d *Daemon) connect(hello *Hello) *session {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.nextSession++
+	d.lastSeen = time.Now()
+	session := &session{id: -d.nextSession, connectedAt: time.Now(), throttle: hackatime.NewThrottle()}
+	if hello != nil && hello.Pid > 0 {
+		session.id = hello.Pid
+		session.workspace = hello.Workspace
+	}
+	d.sessions[session] = true
+	d.activity(session.workspace).sessions++
+	slog.Info("DaemonClients", "clients", len(d.sessions), "session", session.id, "workspace", session.workspace)
+	return session
+}
+
+func (d *Daemon) recordMetrics(session *session, counts Counts) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	session.metrics = &counts
+}
+
+func (d *Daemon) disconnect(session *session) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	delete(d.sessions, session)
+	d.activity(session.workspace).sessions--
+	d.lastSeen = time.Now()
+	slog.Info("DaemonClients", "clients", len(d.sessions), "session", session.id, "workspace", session.workspace)
+}
+
+func (d *Daemon) closeWhenIdle(listener net.Listener, idleTimeout time.Duration) {
+	ticker := time.NewTicker(min(idleCheckInterval, idleTimeout))
+	defer ticker.Stop()
+
+	for range ticker.C {
+		d.mutex.Lock()
+		idle := len(d.sessions) == 0 && time.Since(d.lastSeen) >= idleTimeout
+		d.mutex.Unlock()
+
+		if idle {
+			slog.Info("DaemonIdle", "idle_timeout", idleTimeout.String())
+			listener.Close()
+			return
+		}
+	}
+}
+
+func dial(path string) (net.Conn, error) {
+	if pipe.IsPath(path) {
+		return pipe.Dial(path, dialTimeout)
+	}
+	return net.DialTimeout("unix", path, dialTimeout)
+}
+
+func requestHandover(path string) (Handover, error) {
+	conn, err := dial(path)
+	if err != nil {
+		return Handover{}, err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(handoverTimeout))
+	if err := json.NewEncoder(conn).Encode(request{Handover: true}); err != nil {
+		return Handover{}, err
+	}
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return Handover{}, err
+	}
+	if resp.Handover == nil {
+		return Handover{}, errors.New("daemon did not answer the handover")
+	}
+	return *resp.Handover, nil
+}
@@ -0,0 +1,171 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/version"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+type Health struct {
+	Version     string     `json:"version"`
+	Protocol    int        `json:"protocol"`
+	Pid         int        `json:"pid"`
+	StartedAt   time.Time  `json:"started_at"`
+	Uptime      float64    `json:"uptime_seconds"`
+	Clients     int        `json:"clients"`
+	Queued      int        `json:"queued"`
+	Spilled     bool       `json:"spilled"`
+	Received    int        `json:"received"`
+	Throttled   int        `json:"throttled"`
+	Deduped     int        `json:"deduped"`
+	Sent        int        `json:"sent"`
+	Failed      int        `json:"failed"`
+	Timeouts    int        `json:"timeouts"`
+	LastSent    *time.Time `json:"last_sent"`
+	LastError   string     `json:"last_error"`
+	LastErrorAt *time.Time `json:"last_error_at"`
+	Windows     Counts     `json:"windows"`
+	Sessions    []Session  `json:"sessions"`
+}
+
+type Counts struct {
+	Queued    int `json:"queued"`
+	Sent      int `json:"sent"`
+	Failed    int `json:"failed"`
+	Deduped   int `json:"deduped"`
+	Throttled int `json:"throttled"`
+	Dropped   int `json:"dropped"`
+	Timeouts  int `json:"timeouts"`
+}
+
+func (c *Counts) add(other Counts) {
+	c.Queued += other.Queued
+	c.Sent += other.Sent
+	c.Failed += other.Failed
+	c.Deduped += other.Deduped
+	c.Throttled += other.Throttled
+	c.Dropped += other.Dropped
+	c.Timeouts += other.Timeouts
+}
+
+type Session struct {
+	Pid         int       `json:"pid"`
+	Workspace   string    `json:"workspace"`
+	ConnectedAt time.Time `json:"connected_at"`
+	Received    int       `json:"received"`
+	Metrics     *Counts   `json:"metrics"`
+}
+
+type stats struct {
+	received    int
+	throttled   int
+	deduped     int
+	sent        int
+	failed      int
+	timeouts    int
+	lastSent    *time.Time
+	lastError   string
+	lastErrorAt *time.Time
+}
+
+func (d *Daemon) RecordSend(heartbeats []hackatime.Heartbeat, err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	now := time.Now()
+	if hackatime.IsTimeout(err) {
+		d.stats.timeouts++
+	}
+	var partial *hackatime.PartialError
+	if errors.As(err, &partial) {
+		d.stats.sent += len(partial.Accepted(heartbeats))
+		d.stats.failed += len(partial.Rejected)
+	}
+	if err != nil {
+		if _, retry := hackatime.RetryDelay(err); !retry && partial == nil {
+			d.stats.failed += len(heartbeats)
+		}
+		d.stats.lastError = err.Error()
+		d.stats.lastErrorAt = &now
+		return
+	}
+	d.stats.sent += len(heartbeats)
+	d.stats.lastSent = &now
+}
+
+func (d *Daemon) count(f func(stats *stats)) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	f(&d.stats)
+}
+
+func (d *Daemon) Health() Health {
+	queued, spilled := d.queue.Len(), d.queue.Spilled()
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	health := Health{
+		Version:     version.Version,
+		Protocol:    ProtocolVersion,
+		Pid:         os.Getpid(),
+		StartedAt:   d.startedAt,
+		Uptime:      time.Since(d.startedAt).Seconds(),
+		Clients:     len(d.sessions),
+		Queued:      queued,
+		Spilled:     spilled,
+		Received:    d.stats.received,
+		Throttled:   d.stats.throttled,
+		Deduped:     d.stats.deduped,
+		Sent:        d.stats.sent,
+		Failed:      d.stats.failed,
+		Timeouts:    d.stats.timeouts,
+		LastSent:    d.stats.lastSent,
+		LastError:   d.stats.lastError,
+		LastErrorAt: d.stats.lastErrorAt,
+		Sessions:    []Session{},
+	}
+	for session := range d.sessions {
+		health.Sessions = append(health.Sessions, Session{
+			Pid:         session.id,
+			Workspace:   session.workspace,
+			ConnectedAt: session.connectedAt,
+			Received:    session.received,
+			Metrics:     session.metrics,
+		})
+		if session.metrics != nil {
+			health.Windows.add(*session.metrics)
+		}
+	}
+	slices.SortFunc(health.Sessions, func(a, b Session) int {
+		return a.ConnectedAt.Compare(b.ConnectedAt)
+	})
+	return health
+}
+
+func Ping(path string) (Health, error) {
+	conn, err := dial(path)
+	if err != nil {
+		return Health{}, err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+	if err := json.NewEncoder(conn).Encode(request{Ping: true}); err != nil {
+		return Health{}, err
+	}
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return Health{}, err
+	}
+	if resp.Health == nil {
+		return Health{}, errors.New("daemon did not answer the ping")
+	}
+	return *resp.Health, nil
+}
@@ -0,0 +1,56 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/version"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+const ProtocolVersion = 1
+
+type Hello struct {
+	Version   string `json:"version"`
+	Protocol  int    `json:"protocol"`
+	Pid       int    `json:"pid"`
+	Workspace string `json:"workspace,omitempty"`
+}
+
+type request struct {
+	Hello     *Hello               `json:"hello,omitempty"`
+	Heartbeat *hackatime.Heartbeat `json:"heartbeat,omitempty"`
+	Ping      bool                 `json:"ping,omitempty"`
+	Handover  bool                 `json:"handover,omitempty"`
+	Status    bool                 `json:"status,omitempty"`
+	Metrics   *Counts              `json:"metrics,omitempty"`
+}
+
+type response struct {
+	Hello    *Hello           `json:"hello,omitempty"`
+	Health   *Health          `json:"health,omitempty"`
+	Handover *Handover        `json:"handover,omitempty"`
+	Status   *WorkspaceStatus `json:"status,omitempty"`
+}
+
+type Handover struct {
+	Queued int    `json:"queued"`
+	Error  string `json:"error,omitempty"`
+}
+
+func currentHello() *Hello {
+	return &Hello{Version: version.Version, Protocol: ProtocolVersion, Pid: os.Getpid()}
+}
+
+func (h Hello) compatible(other Hello) bool {
+	return h.Protocol == other.Protocol && h.Version == other.Version
+}
+
+type VersionMismatchError struct {
+	Daemon Hello
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("daemon (pid %d) is version %s, protocol %d; this is version %s, protocol %d",
+		e.Daemon.Pid, e.Daemon.Version, e.Daemon.Protocol, version.Version, ProtocolVersion)
+}
@@ -0,0 +1,65 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+const defaultKeystrokeTimeoutMinutes = 15
+
+type WorkspaceStatus struct {
+	Workspace    string  `json:"workspace"`
+	Sessions     int     `json:"sessions"`
+	Heartbeats   int     `json:"heartbeats"`
+	TodaySeconds float64 `json:"today_seconds"`
+	Today        string  `json:"today"`
+}
+
+type workspaceActivity struct {
+	sessions   int
+	day        string
+	heartbeats []hackatime.Heartbeat
+}
+
+func (d *Daemon) activity(workspace string) *workspaceActivity {
+	activity, exists := d.workspaces[workspace]
+	if !exists {
+		activity = &workspaceActivity{}
+		d.workspaces[workspace] = activity
+	}
+
+	if today := time.Now().Format(time.DateOnly); activity.day != today {
+		activity.day = today
+		activity.heartbeats = nil
+	}
+	return activity
+}
+
+func (d *Daemon) recordWorkspace(session *session, hb hackatime.Heartbeat) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	activity := d.activity(session.workspace)
+	activity.heartbeats = append(activity.heartbeats, hb)
+}
+
+func (d *Daemon) workspaceStatus(session *session) WorkspaceStatus {
+	var workspace string
+	if session != nil {
+		workspace = session.workspace
+	}
+
+	d.mutex.Lock()
+	activity := d.activity(workspace)
+	status := WorkspaceStatus{Workspace: workspace, Sessions: activity.sessions, Heartbeats: len(activity.heartbeats)}
+	heartbeats := append([]hackatime.Heartbeat(nil), activity.heartbeats...)
+	d.mutex.Unlock()
+
+	timeout := time.Duration(config.Int("keystroke_timeout", defaultKeystrokeTimeoutMinutes)) * time.Minute
+	total := hackatime.TotalDuration(heartbeats, timeout)
+	status.TodaySeconds = total.Seconds()
+	status.Today = hackatime.FormatDuration(total)
+	return status
+}
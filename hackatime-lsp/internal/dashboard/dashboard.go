@@ -0,0 +1,137 @@
+package dashboard
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+//go:embed index.html
+var indexHTML []byte
+
+type Dashboard struct {
+	History *hackatime.Store
+	Timeout time.Duration
+}
+
+type span struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Project  string    `json:"project"`
+	Language string    `json:"language"`
+}
+
+type summary struct {
+	Start     time.Time               `json:"start"`
+	Total     string                  `json:"total"`
+	Timeline  []span                  `json:"timeline"`
+	Projects  []hackatime.SummaryItem `json:"projects"`
+	Languages []hackatime.SummaryItem `json:"languages"`
+}
+
+func (d *Dashboard) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(indexHTML)
+	})
+	mux.HandleFunc("/api/summary", d.serveSummary)
+	return mux
+}
+
+func LoopbackAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if host == "" || strings.EqualFold(host, "localhost") {
+		return net.JoinHostPort("127.0.0.1", port), nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return addr, nil
+	}
+	return "", fmt.Errorf("%s is not a loopback address", addr)
+}
+
+func (d *Dashboard) Serve(addr string) error {
+	addr, err := LoopbackAddr(addr)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	slog.Info("Dashboard", "url", "http://"+listener.Addr().String())
+
+	go func() {
+		if err := http.Serve(listener, d.Handler()); err != nil {
+			slog.Warn("DashboardStopped", "error", err)
+		}
+	}()
+	return nil
+}
+
+func (d *Dashboard) serveSummary(w http.ResponseWriter, r *http.Request) {
+	days, err := strconv.Atoi(r.URL.Query().Get("days"))
+	if err != nil || days < 1 {
+		days = 1
+	}
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day()-(days-1), 0, 0, 0, 0, now.Location())
+
+	heartbeats, err := d.History.Read(func(hb hackatime.Heartbeat) bool {
+		return hb.Time >= float64(start.Unix())
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	durations := hackatime.Durations(heartbeats, d.Timeout)
+	result := summary{
+		Start: start,
+		Projects: hackatime.DurationItems(hackatime.DurationsBy(durations, func(d hackatime.Duration) string {
+			return orUnknown(d.Project)
+		})),
+		Languages: hackatime.DurationItems(hackatime.DurationsBy(durations, func(d hackatime.Duration) string {
+			return orUnknown(hackatime.WakatimeLanguage(d.Language))
+		})),
+	}
+
+	var total time.Duration
+	for _, d := range durations {
+		total += d.Duration
+		if d.Duration > 0 {
+			result.Timeline = append(result.Timeline, span{
+				Start:    d.Start,
+				End:      d.End(),
+				Project:  orUnknown(d.Project),
+				Language: orUnknown(hackatime.WakatimeLanguage(d.Language)),
+			})
+		}
+	}
+	result.Total = hackatime.FormatDuration(total)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func orUnknown(name string) string {
+	if name == "" {
+		return "Unknown"
+	}
+	return name
+}
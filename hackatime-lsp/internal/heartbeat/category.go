@@ -0,0 +1,110 @@
+package heartbeat
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+const (
+	CategoryCoding        = "coding"
+	CategoryWritingTests  = "writing tests"
+	CategoryWritingDocs   = "writing docs"
+	CategoryCodeReviewing = "code reviewing"
+	CategoryBrowsing      = "browsing"
+)
+
+var reviewBufferNames = map[string]bool{
+	"COMMIT_EDITMSG":   true,
+	"MERGE_MSG":        true,
+	"SQUASH_MSG":       true,
+	"TAG_EDITMSG":      true,
+	"PULLREQ_EDITMSG":  true,
+	"EDIT_DESCRIPTION": true,
+}
+
+var docExtensions = map[string]bool{
+	".md":       true,
+	".markdown": true,
+	".mdx":      true,
+	".rst":      true,
+	".adoc":     true,
+	".asciidoc": true,
+}
+
+var testFilePattern = regexp.MustCompile(`(?i)(_test\.go|\.(spec|test)\.[cm]?[jt]sx?|_spec\.rb|_test\.py|Test\.java|Tests?\.cs)$|^test_.*\.py$`)
+
+func DetectCategory(entity, projectRoot string, settings config.Settings) string {
+	if isReviewBuffer(entity) {
+		return CategoryCodeReviewing
+	}
+	if settings.Bool("detect_test_category", true) && isTestFile(entity, projectRoot) {
+		return CategoryWritingTests
+	}
+	if settings.Bool("detect_docs_category", true) && isDocFile(entity, projectRoot) {
+		return CategoryWritingDocs
+	}
+	return CategoryCoding
+}
+
+func isTestFile(entity, projectRoot string) bool {
+	if testFilePattern.MatchString(filepath.Base(entity)) {
+		return true
+	}
+
+	for _, dir := range projectDirs(entity, projectRoot) {
+		if dir == "__tests__" {
+			return true
+		}
+	}
+	return false
+}
+
+func isDocFile(entity, projectRoot string) bool {
+	if docExtensions[strings.ToLower(filepath.Ext(entity))] {
+		return true
+	}
+
+	for _, dir := range projectDirs(entity, projectRoot) {
+		if dir == "docs" || dir == "doc" {
+			return true
+		}
+	}
+	return false
+}
+
+func projectDirs(entity, projectRoot string) []string {
+	if projectRoot == "" {
+		return nil
+	}
+	rel, err := filepath.Rel(projectRoot, filepath.Dir(entity))
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return nil
+	}
+	return strings.Split(filepath.ToSlash(rel), "/")
+}
+
+func isReviewBuffer(entity string) bool {
+	name := filepath.Base(entity)
+	return reviewBufferNames[name] || strings.HasSuffix(name, ".diff") || strings.HasSuffix(name, ".patch")
+}
+
+func AttributeReviewBuffer(hb hackatime.Heartbeat) hackatime.Heartbeat {
+	if hb.Category != CategoryCodeReviewing {
+		return hb
+	}
+
+	dir := filepath.Dir(hb.Entity)
+	if filepath.Base(dir) == ".git" {
+		dir = filepath.Dir(dir)
+	}
+
+	if root := findGitRoot(dir); root != "" {
+		hb.AlternateProject = filepath.Base(root)
+		hb.ProjectFolder = root
+	}
+	return hb
+}
@@ -0,0 +1,173 @@
+package heartbeat
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+const builtinSkipPatterns = `
+node_modules/
+vendor/
+dist/
+*.min.js
+*.min.css
+*.map
+*.lock
+package-lock.json
+`
+
+const defaultMaxFileSizeMB = 5
+
+const (
+	FileActionSkip      = "skip"
+	FileActionDowngrade = "downgrade"
+)
+
+var binaryExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".bmp": true, ".ico": true, ".webp": true,
+	".pdf": true, ".zip": true, ".gz": true, ".tar": true, ".7z": true, ".rar": true, ".xz": true,
+	".exe": true, ".dll": true, ".so": true, ".dylib": true, ".a": true, ".o": true, ".class": true,
+	".wasm": true, ".mp3": true, ".mp4": true, ".mov": true, ".wav": true, ".ttf": true, ".otf": true,
+	".woff": true, ".woff2": true, ".sqlite": true, ".db": true, ".bin": true,
+}
+
+var builtinSkipRules = parseIgnoreRules(builtinSkipPatterns)
+
+var (
+	patternCache      = make(map[string]*regexp.Regexp)
+	patternCacheMutex sync.Mutex
+)
+
+func SkipReason(hb hackatime.Heartbeat, projectRoot string, settings config.Settings) string {
+	if matchesPatternList(config.Value("exclude"), hb.Entity) && !matchesPatternList(config.Value("include"), hb.Entity) {
+		return "excluded by config"
+	}
+	isFile := hb.EntityType == "" || hb.EntityType == "file"
+	if isFile && isHackatimeIgnored(projectRoot, hb.Entity) {
+		return "ignored by .hackatimeignore"
+	}
+	if !isLanguageTracked(hb.Language, settings) {
+		return "language " + hb.Language + " not tracked"
+	}
+	if settings.Bool("exclude_unknown_project", false) && isUnknownProject(hb, projectRoot) {
+		return "unknown project"
+	}
+	if isFile && settings.Bool("skip_generated", true) && matchesBuiltinSkipList(projectRoot, hb.Entity) {
+		return "generated or vendored file"
+	}
+	if isFile && settings.Bool("respect_gitignore", false) && isGitIgnored(hb.Entity) {
+		return "ignored by .gitignore"
+	}
+	return ""
+}
+
+func LargeFileAction(size int64) string {
+	maxBytes := int64(config.Int("max_file_size_mb", defaultMaxFileSizeMB)) * 1024 * 1024
+	if size <= maxBytes {
+		return ""
+	}
+	if config.Value("large_file_action") == FileActionSkip {
+		return FileActionSkip
+	}
+	return FileActionDowngrade
+}
+
+func FileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+func HasBinaryExtension(path string) bool {
+	return binaryExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+func isLanguageTracked(language string, settings config.Settings) bool {
+	if language == "" {
+		return true
+	}
+
+	mapped := hackatime.WakatimeLanguage(language)
+	if include := settings.List("include_languages"); len(include) > 0 {
+		return containsFold(include, language) || containsFold(include, mapped)
+	}
+
+	exclude := settings.List("exclude_languages")
+	return !containsFold(exclude, language) && !containsFold(exclude, mapped)
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func isUnknownProject(hb hackatime.Heartbeat, projectRoot string) bool {
+	if hb.AlternateProject != "" || projectRoot != "" {
+		return false
+	}
+	if hb.EntityType != "file" || !filepath.IsAbs(hb.Entity) {
+		return true
+	}
+	return findGitRoot(filepath.Dir(hb.Entity)) == ""
+}
+
+func matchesBuiltinSkipList(projectRoot, entity string) bool {
+	rel := entity
+	if projectRoot != "" {
+		if r, err := filepath.Rel(projectRoot, entity); err == nil && !strings.HasPrefix(r, "..") {
+			rel = r
+		}
+	}
+
+	for _, rule := range builtinSkipRules {
+		if rule.re.MatchString(filepath.ToSlash(rel)) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesPatternList(patterns, entity string) bool {
+	for _, pattern := range strings.Split(patterns, "\n") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		re := compilePattern(pattern)
+		if re != nil && re.MatchString(entity) {
+			return true
+		}
+	}
+	return false
+}
+
+func compilePattern(pattern string) *regexp.Regexp {
+	patternCacheMutex.Lock()
+	defer patternCacheMutex.Unlock()
+
+	if re, exists := patternCache[pattern]; exists {
+		return re
+	}
+
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		slog.Warn("InvalidPattern", "pattern", pattern, "error", err)
+		re = nil
+	}
+	patternCache[pattern] = re
+	return re
+}
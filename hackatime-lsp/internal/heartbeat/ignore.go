@@ -0,0 +1,313 @@
+package heartbeat
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+type ignoreFile struct {
+	base    string
+	rules   []ignoreRule
+	modTime time.Time
+}
+
+type gitRootLookup struct {
+	root    string
+	checked time.Time
+	done    chan struct{}
+}
+
+const (
+	gitRootCacheTTL   = time.Minute
+	networkLookupWait = 100 * time.Millisecond
+)
+
+var (
+	ignoreFileCache      = make(map[string]*ignoreFile)
+	ignoreFileCacheMutex sync.Mutex
+
+	gitRootCache      = make(map[string]*gitRootLookup)
+	gitRootCacheMutex sync.Mutex
+)
+
+func loadIgnoreFile(path, base string) *ignoreFile {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return nil
+	}
+
+	ignoreFileCacheMutex.Lock()
+	defer ignoreFileCacheMutex.Unlock()
+
+	if cached, exists := ignoreFileCache[path]; exists && cached.modTime.Equal(info.ModTime()) {
+		return cached
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	file := &ignoreFile{
+		base:    base,
+		rules:   parseIgnoreRules(string(data)),
+		modTime: info.ModTime(),
+	}
+	ignoreFileCache[path] = file
+	return file
+}
+
+func parseIgnoreRules(data string) []ignoreRule {
+	var rules []ignoreRule
+
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimRight(line, " ")
+
+		rule := ignoreRule{}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+
+		expr := globToRegexp(line)
+		if !anchored {
+			expr = "(.*/)?" + expr
+		}
+		if rule.dirOnly {
+			expr = "^" + expr + "/.*$"
+		} else {
+			expr = "^" + expr + "(/.*)?$"
+		}
+
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			continue
+		}
+		rule.re = re
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+
+	for i := 0; i < len(glob); i++ {
+		ch := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			sb.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case ch == '*':
+			sb.WriteString("[^/]*")
+		case ch == '?':
+			sb.WriteString("[^/]")
+		case ch == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end
+		case ch == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+
+	return sb.String()
+}
+
+func matchIgnoreFiles(files []*ignoreFile, entity string) bool {
+	ignored := false
+
+	for _, file := range files {
+		rel, err := filepath.Rel(file.base, entity)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+
+		for _, rule := range file.rules {
+			if rule.re.MatchString(rel) {
+				ignored = !rule.negate
+			}
+		}
+	}
+
+	return ignored
+}
+
+func IsNetworkPath(path string) bool {
+	return networkRoot(path) != ""
+}
+
+func findGitRoot(dir string) string {
+	share := networkRoot(dir)
+	if share == "" {
+		return walkGitRoot(dir, "")
+	}
+
+	gitRootCacheMutex.Lock()
+	lookup, exists := gitRootCache[dir]
+	if !exists {
+		lookup = startGitRootLookup(dir, share)
+		gitRootCache[dir] = lookup
+	} else if lookup.finished() && time.Since(lookup.checked) > gitRootCacheTTL {
+		gitRootCache[dir] = startGitRootLookup(dir, share)
+	}
+	gitRootCacheMutex.Unlock()
+
+	select {
+	case <-lookup.done:
+		return lookup.root
+	case <-time.After(networkLookupWait):
+		return ""
+	}
+}
+
+func startGitRootLookup(dir, share string) *gitRootLookup {
+	lookup := &gitRootLookup{done: make(chan struct{})}
+	go func() {
+		lookup.root = walkGitRoot(dir, share)
+		lookup.checked = time.Now()
+		close(lookup.done)
+	}()
+	return lookup
+}
+
+func (l *gitRootLookup) finished() bool {
+	select {
+	case <-l.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func walkGitRoot(dir, stop string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir || dir == stop {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func GitBranch(path string) string {
+	if !filepath.IsAbs(path) {
+		return ""
+	}
+	root := findGitRoot(filepath.Dir(path))
+	if root == "" {
+		return ""
+	}
+
+	gitDir := filepath.Join(root, ".git")
+	if data, err := os.ReadFile(gitDir); err == nil {
+		dir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+		if !ok {
+			return ""
+		}
+		gitDir = strings.TrimSpace(dir)
+		if !filepath.IsAbs(gitDir) {
+			gitDir = filepath.Join(root, gitDir)
+		}
+	}
+
+	head, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	branch, _ := strings.CutPrefix(strings.TrimSpace(string(head)), "ref: refs/heads/")
+	if branch == strings.TrimSpace(string(head)) {
+		return ""
+	}
+	return branch
+}
+
+func isGitIgnored(entity string) bool {
+	root := findGitRoot(filepath.Dir(entity))
+	if root == "" {
+		return false
+	}
+
+	var files []*ignoreFile
+	dir := root
+	rel, err := filepath.Rel(root, filepath.Dir(entity))
+	if err != nil {
+		return false
+	}
+
+	for _, part := range append([]string{""}, strings.Split(rel, string(filepath.Separator))...) {
+		if part != "" && part != "." {
+			dir = filepath.Join(dir, part)
+		}
+		if file := loadIgnoreFile(filepath.Join(dir, ".gitignore"), dir); file != nil {
+			files = append(files, file)
+		}
+	}
+	if file := loadIgnoreFile(filepath.Join(root, ".git", "info", "exclude"), root); file != nil {
+		files = append([]*ignoreFile{file}, files...)
+	}
+
+	return matchIgnoreFiles(files, entity)
+}
+
+func isHackatimeIgnored(projectRoot, entity string) bool {
+	if projectRoot == "" {
+		return false
+	}
+
+	file := loadIgnoreFile(filepath.Join(projectRoot, ".hackatimeignore"), projectRoot)
+	if file == nil {
+		return false
+	}
+	return matchIgnoreFiles([]*ignoreFile{file}, entity)
+}
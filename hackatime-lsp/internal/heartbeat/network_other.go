@@ -0,0 +1,7 @@
+//go:build !windows
+
+package heartbeat
+
+func networkRoot(path string) string {
+	return ""
+}
@@ -0,0 +1,23 @@
+package heartbeat
+
+import (
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+func networkRoot(path string) string {
+	volume := filepath.VolumeName(path)
+	if strings.HasPrefix(volume, `\\`) {
+		return volume + `\`
+	}
+	if len(volume) != 2 || volume[1] != ':' {
+		return ""
+	}
+	root, err := windows.UTF16PtrFromString(volume + `\`)
+	if err != nil || windows.GetDriveType(root) != windows.DRIVE_REMOTE {
+		return ""
+	}
+	return volume + `\`
+}
@@ -0,0 +1,21 @@
+package heartbeat
+
+import (
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+func MapContainerPaths(hb hackatime.Heartbeat) hackatime.Heartbeat {
+	if hb.EntityType != "file" {
+		return hb
+	}
+
+	if entity := config.MapPath(hb.Entity); entity != hb.Entity {
+		if hb.LocalFile == "" {
+			hb.LocalFile = hb.Entity
+		}
+		hb.Entity = entity
+	}
+	hb.ProjectFolder = config.MapPath(hb.ProjectFolder)
+	return hb
+}
@@ -0,0 +1,84 @@
+package heartbeat
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+func shouldHideFileName(entity string) bool {
+	value := config.Value("hide_file_names")
+	if value == "" {
+		return false
+	}
+	if hide, err := strconv.ParseBool(value); err == nil {
+		return hide
+	}
+	return matchesPatternList(value, entity)
+}
+
+func HideFileName(hb hackatime.Heartbeat) hackatime.Heartbeat {
+	if hb.EntityType != "file" || !shouldHideFileName(hb.Entity) {
+		return hb
+	}
+
+	original := hb.Entity
+	if hb.LocalFile == "" {
+		hb.LocalFile = original
+	}
+
+	root := hb.ProjectFolder
+	if root == "" {
+		root = filepath.Dir(original)
+	}
+
+	switch config.Value("hide_file_names_mode") {
+	case "folder":
+		rel, err := filepath.Rel(root, filepath.Dir(original))
+		if err != nil || strings.HasPrefix(rel, "..") {
+			rel = "."
+		}
+		project := hb.AlternateProject
+		if project == "" {
+			project = filepath.Base(root)
+		}
+		hb.Entity = filepath.ToSlash(filepath.Join(project, rel))
+	default:
+		sum := sha256.Sum256([]byte(original))
+		hb.Entity = filepath.Join(root, "HIDDEN-"+hex.EncodeToString(sum[:6])+filepath.Ext(original))
+	}
+
+	return hb
+}
+
+func HideHomeDir(hb hackatime.Heartbeat, settings config.Settings) hackatime.Heartbeat {
+	if hb.EntityType != "file" || !settings.Bool("hide_home_dir", false) {
+		return hb
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return hb
+	}
+	rel, ok := strings.CutPrefix(hb.Entity, filepath.Clean(home)+string(filepath.Separator))
+	if !ok {
+		return hb
+	}
+
+	if hb.LocalFile == "" {
+		hb.LocalFile = hb.Entity
+	}
+	switch settings.String("hide_home_dir_mode") {
+	case "strip":
+		hb.Entity = rel
+	default:
+		hb.Entity = "~" + string(filepath.Separator) + rel
+	}
+	return hb
+}
@@ -0,0 +1,144 @@
+package instance
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+const (
+	lockTimeout    = 200 * time.Millisecond
+	lockRetry      = 10 * time.Millisecond
+	staleLockAfter = 5 * time.Second
+)
+
+type owner struct {
+	Pid  int       `json:"pid"`
+	Time time.Time `json:"time"`
+}
+
+type Registry struct {
+	path     string
+	pid      int
+	interval time.Duration
+}
+
+func Open(dir, workspace string, interval time.Duration) *Registry {
+	sum := sha1.Sum([]byte(workspace))
+	return &Registry{
+		path:     filepath.Join(dir, hex.EncodeToString(sum[:])[:16]+".json"),
+		pid:      os.Getpid(),
+		interval: interval,
+	}
+}
+
+func (r *Registry) Claim(entity string, isWrite bool) bool {
+	unlock, err := r.lock()
+	if err != nil {
+		return true
+	}
+	defer unlock()
+
+	owners := r.read()
+	now := time.Now()
+	if current, exists := owners[entity]; exists && !isWrite && current.Pid != r.pid &&
+		now.Sub(current.Time) < r.interval && Alive(current.Pid) {
+		return false
+	}
+
+	for key, current := range owners {
+		if now.Sub(current.Time) >= r.interval {
+			delete(owners, key)
+		}
+	}
+	owners[entity] = owner{Pid: r.pid, Time: now}
+	r.write(owners)
+	return true
+}
+
+func (r *Registry) Release() {
+	unlock, err := r.lock()
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	owners := r.read()
+	for key, current := range owners {
+		if current.Pid == r.pid {
+			delete(owners, key)
+		}
+	}
+	if len(owners) == 0 {
+		os.Remove(r.path)
+		return
+	}
+	r.write(owners)
+}
+
+func (r *Registry) read() map[string]owner {
+	owners := make(map[string]owner)
+	if data, err := os.ReadFile(r.path); err == nil {
+		json.Unmarshal(data, &owners)
+	}
+	return owners
+}
+
+func (r *Registry) write(owners map[string]owner) {
+	data, err := json.Marshal(owners)
+	if err != nil {
+		return
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+	os.Rename(tmp, r.path)
+}
+
+func (r *Registry) lock() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return nil, err
+	}
+
+	lockPath := r.path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > staleLockAfter {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New("instance registry is locked")
+		}
+		time.Sleep(lockRetry)
+	}
+}
+
+func Alive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
@@ -0,0 +1,54 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const crashFileName = "hackatime-zed-crash.log"
+
+var (
+	crashFile  string
+	crashMutex sync.Mutex
+)
+
+func setCrashFile(path string) {
+	crashMutex.Lock()
+	defer crashMutex.Unlock()
+
+	crashFile = path
+}
+
+func CrashFile() string {
+	crashMutex.Lock()
+	defer crashMutex.Unlock()
+
+	return crashFile
+}
+
+func WriteCrash(source string, recovered any, stack []byte) {
+	slog.Error("Panic", "source", source, "error", fmt.Sprint(recovered), "crash_file", CrashFile())
+
+	crashMutex.Lock()
+	defer crashMutex.Unlock()
+
+	if crashFile == "" {
+		return
+	}
+
+	file, err := os.OpenFile(crashFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	fmt.Fprintf(file, "=== %s panic in %s: %v\n%s\n", time.Now().Format(time.RFC3339), source, recovered, stack)
+}
+
+func crashFileFor(logFile string) string {
+	return filepath.Join(filepath.Dir(logFile), crashFileName)
+}
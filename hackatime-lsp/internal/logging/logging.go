@@ -0,0 +1,200 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+)
+
+const (
+	DefaultMaxSizeMB  = 10
+	DefaultMaxBackups = 3
+	DefaultMaxAgeDays = 7
+)
+
+var (
+	level     = new(slog.LevelVar)
+	forwarder atomic.Pointer[Forwarder]
+)
+
+type Forwarder func(level slog.Level, message string)
+
+type Options struct {
+	Level    string
+	File     string
+	Disabled bool
+	Rotation Rotation
+}
+
+type Rotation struct {
+	MaxSize    int64
+	MaxBackups int
+	MaxAge     time.Duration
+}
+
+type fileWriter struct {
+	mutex    sync.Mutex
+	path     string
+	rotation Rotation
+}
+
+func (w *fileWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.rotation.MaxSize > 0 {
+		if info, err := os.Stat(w.path); err == nil && info.Size()+int64(len(p)) > w.rotation.MaxSize {
+			w.rotate()
+		}
+	}
+
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	return file.Write(p)
+}
+
+func (w *fileWriter) rotate() {
+	for i := w.rotation.MaxBackups; i > 0; i-- {
+		src := w.path
+		if i > 1 {
+			src = w.backupPath(i - 1)
+		}
+		dst := w.backupPath(i)
+		os.Remove(dst)
+		os.Rename(src, dst)
+	}
+	if w.rotation.MaxBackups <= 0 {
+		os.Remove(w.path)
+	}
+	w.prune()
+}
+
+func (w *fileWriter) backupPath(n int) string {
+	return w.path + "." + strconv.Itoa(n)
+}
+
+func (w *fileWriter) prune() {
+	backups, _ := filepath.Glob(w.path + ".*")
+	for _, backup := range backups {
+		n, err := strconv.Atoi(strings.TrimPrefix(backup, w.path+"."))
+		if err != nil {
+			continue
+		}
+
+		if n > w.rotation.MaxBackups {
+			os.Remove(backup)
+			continue
+		}
+		if info, err := os.Stat(backup); err == nil && w.rotation.MaxAge > 0 && time.Since(info.ModTime()) > w.rotation.MaxAge {
+			os.Remove(backup)
+		}
+	}
+}
+
+func DefaultFile() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "hackatime-zed.log")
+	}
+	return filepath.Join(homeDir, "hackatime-zed.log")
+}
+
+func Setup(opts Options) error {
+	if err := level.UnmarshalText([]byte(opts.Level)); err != nil {
+		return err
+	}
+
+	if opts.Disabled {
+		setCrashFile("")
+		slog.SetDefault(slog.New(forwardHandler{slog.DiscardHandler}))
+		return nil
+	}
+
+	path := config.ExpandHome(opts.File)
+	if path == "" {
+		path = DefaultFile()
+	}
+
+	writer := &fileWriter{
+		path:     path,
+		rotation: opts.Rotation,
+	}
+	writer.prune()
+	setCrashFile(crashFileFor(path))
+	slog.SetDefault(slog.New(forwardHandler{slog.NewJSONHandler(writer, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: replaceAttr,
+	})}))
+	return nil
+}
+
+func Forward(f Forwarder) {
+	if f == nil {
+		forwarder.Store(nil)
+		return
+	}
+	forwarder.Store(&f)
+}
+
+type forwardHandler struct {
+	slog.Handler
+}
+
+func (h forwardHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.Handler.Enabled(ctx, level) || (level >= slog.LevelWarn && forwarder.Load() != nil)
+}
+
+func (h forwardHandler) Handle(ctx context.Context, record slog.Record) error {
+	if f := forwarder.Load(); f != nil && record.Level >= slog.LevelWarn {
+		(*f)(record.Level, formatRecord(record))
+	}
+	if !h.Handler.Enabled(ctx, record.Level) {
+		return nil
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h forwardHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return forwardHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h forwardHandler) WithGroup(name string) slog.Handler {
+	return forwardHandler{h.Handler.WithGroup(name)}
+}
+
+func formatRecord(record slog.Record) string {
+	var message strings.Builder
+	message.WriteString(record.Message)
+	record.Attrs(func(attr slog.Attr) bool {
+		fmt.Fprintf(&message, " %s=%v", attr.Key, attr.Value)
+		return true
+	})
+	return message.String()
+}
+
+func replaceAttr(groups []string, attr slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return attr
+	}
+
+	switch attr.Key {
+	case slog.TimeKey:
+		attr.Key = "timestamp"
+	case slog.MessageKey:
+		attr.Key = "event"
+	}
+	return attr
+}
@@ -0,0 +1,18 @@
+package logging
+
+import (
+	"log/slog"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+func Rejected(rejections []hackatime.Rejection, attrs ...any) {
+	for _, rejection := range rejections {
+		event := "HeartbeatRejected"
+		if rejection.Status == 0 {
+			event = "HeartbeatFailed"
+		}
+		hb := rejection.Heartbeat
+		slog.Warn(event, append([]any{"entity", hb.Entity, "project", hb.AlternateProject, "status", rejection.Status, "result", rejection.Reason, "heartbeat", hb}, attrs...)...)
+	}
+}
@@ -0,0 +1,48 @@
+package lspserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+const (
+	slowSendThreshold = 15 * time.Second
+	maxBackpressure   = 4
+)
+
+type backpressure struct {
+	mutex  sync.Mutex
+	factor int
+}
+
+func (s *Server) recordSendTime(elapsed time.Duration, err error) {
+	slow := elapsed >= slowSendThreshold || errors.Is(err, context.DeadlineExceeded)
+
+	s.backpressure.mutex.Lock()
+	factor := max(s.backpressure.factor, 1)
+	if slow {
+		factor = min(factor*2, maxBackpressure)
+	} else if err == nil {
+		factor = 1
+	}
+	changed := factor != max(s.backpressure.factor, 1)
+	s.backpressure.factor = factor
+	s.backpressure.mutex.Unlock()
+
+	if !changed {
+		return
+	}
+
+	interval := hackatime.Interval * time.Duration(factor)
+	s.throttle.SetInterval(interval)
+	if slow {
+		slog.Warn("Backpressure", "elapsed", elapsed.String(), "throttle", interval.String(), "result", err)
+	} else {
+		slog.Info("BackpressureRelieved", "throttle", interval.String())
+	}
+}
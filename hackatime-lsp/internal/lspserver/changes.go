@@ -0,0 +1,149 @@
+package lspserver
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+const (
+	defaultAILineThreshold = 5
+	aiEditMarkerMs         = 5000
+)
+
+type lineChange struct {
+	Added   int
+	Removed int
+	AI      int
+	Human   int
+
+	CharsTyped   int
+	CharsDeleted int
+	EditEvents   int
+}
+
+type aiEditMarker struct {
+	Lines   int
+	Expires time.Time
+}
+
+func (s *Server) markAIEdit(entity string, lines int) {
+	s.lineChangesMutex.Lock()
+	defer s.lineChangesMutex.Unlock()
+
+	s.aiEditsReported = true
+
+	total := s.pendingLineChanges[entity]
+	moved := min(lines, total.Human)
+	total.Human -= moved
+	total.AI += moved
+	s.pendingLineChanges[entity] = total
+
+	if remaining := lines - moved; remaining > 0 {
+		s.aiEditMarkers[entity] = aiEditMarker{
+			Lines:   remaining,
+			Expires: time.Now().Add(aiEditMarkerMs * time.Millisecond),
+		}
+	}
+}
+
+func (s *Server) recordLineChanges(entity string, changes []interface{}) {
+	s.lineChangesMutex.Lock()
+	defer s.lineChangesMutex.Unlock()
+
+	detectAI := s.settings.Bool("detect_ai_changes", true) && !s.aiEditsReported
+	threshold := config.Int("ai_line_threshold", defaultAILineThreshold)
+
+	total := s.pendingLineChanges[entity]
+	for _, change := range changes {
+		changeEvent, ok := change.(protocol.TextDocumentContentChangeEvent)
+		if !ok || changeEvent.Range == nil {
+			continue
+		}
+
+		removed := int(changeEvent.Range.End.Line - changeEvent.Range.Start.Line)
+		added := strings.Count(changeEvent.Text, "\n")
+		total.Removed += removed
+		total.Added += added
+
+		marker, marked := s.aiEditMarkers[entity]
+		if marked && time.Now().After(marker.Expires) {
+			delete(s.aiEditMarkers, entity)
+			marked = false
+		}
+
+		if marked {
+			total.AI += added + removed
+			marker.Lines -= added + removed
+			if marker.Lines <= 0 {
+				delete(s.aiEditMarkers, entity)
+			} else {
+				s.aiEditMarkers[entity] = marker
+			}
+		} else if detectAI && added >= threshold {
+			total.AI += added + removed
+		} else {
+			total.Human += added + removed
+		}
+	}
+	s.pendingLineChanges[entity] = total
+}
+
+func (s *Server) recordCharChanges(entity string, changes []interface{}, deleted int) {
+	s.lineChangesMutex.Lock()
+	defer s.lineChangesMutex.Unlock()
+
+	total := s.pendingLineChanges[entity]
+	for _, change := range changes {
+		switch changeEvent := change.(type) {
+		case protocol.TextDocumentContentChangeEvent:
+			total.CharsTyped += utf8.RuneCountInString(changeEvent.Text)
+		case protocol.TextDocumentContentChangeEventWhole:
+			total.CharsTyped += utf8.RuneCountInString(changeEvent.Text)
+		}
+		total.EditEvents++
+	}
+	total.CharsDeleted += deleted
+	s.pendingLineChanges[entity] = total
+}
+
+func (s *Server) takeLineChanges(entity string) lineChange {
+	s.lineChangesMutex.Lock()
+	defer s.lineChangesMutex.Unlock()
+
+	total := s.pendingLineChanges[entity]
+	delete(s.pendingLineChanges, entity)
+	return total
+}
+
+func applyLineChanges(hb hackatime.Heartbeat, changes lineChange) hackatime.Heartbeat {
+	hb.LineAdditions = changes.Added
+	hb.LineDeletions = changes.Removed
+	hb.AILineChanges = changes.AI
+	hb.HumanLineChanges = changes.Human
+	hb.CharsTyped = changes.CharsTyped
+	hb.CharsDeleted = changes.CharsDeleted
+	hb.EditEvents = changes.EditEvents
+	return hb
+}
+
+func (s *Server) pruneLineChanges(open map[string]bool) {
+	s.lineChangesMutex.Lock()
+	defer s.lineChangesMutex.Unlock()
+
+	for entity := range s.pendingLineChanges {
+		if !open[entity] {
+			delete(s.pendingLineChanges, entity)
+		}
+	}
+	for entity, marker := range s.aiEditMarkers {
+		if time.Now().After(marker.Expires) {
+			delete(s.aiEditMarkers, entity)
+		}
+	}
+}
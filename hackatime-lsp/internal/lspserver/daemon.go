@@ -0,0 +1,122 @@
+package lspserver
+
+import (
+	"log/slog"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/daemon"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/update"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/version"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+const (
+	daemonStartTimeout = 3 * time.Second
+	daemonPollInterval = 100 * time.Millisecond
+)
+
+func (s *Server) startDaemonClient() {
+	if !s.settings.Bool("daemon", false) || s.mockCLI {
+		return
+	}
+
+	path := s.settings.String("daemon_socket")
+	if path == "" {
+		path = config.DaemonSocketPath()
+	}
+	path = config.ExpandHome(path)
+
+	client, err := daemon.Dial(path, s.projectRoot)
+	if err == nil {
+		slog.Info("DaemonConnected", "socket", path)
+		s.daemon.Store(client)
+		return
+	}
+	if mismatch, ok := err.(*daemon.VersionMismatchError); ok {
+		slog.Warn("DaemonVersionMismatch", "socket", path, "daemon_version", mismatch.Daemon.Version, "daemon_pid", mismatch.Daemon.Pid, "version", version.Version)
+		if !update.Newer(version.Version, mismatch.Daemon.Version) {
+			return
+		}
+	}
+
+	go func() {
+		defer recoverPanic("daemon")
+
+		client, err := s.spawnDaemon(path)
+		if err != nil {
+			slog.Warn("DaemonUnavailable", "socket", path, "error", err)
+			return
+		}
+
+		slog.Info("DaemonConnected", "socket", path)
+		s.daemon.Store(client)
+	}()
+}
+
+func (s *Server) spawnDaemon(path string) (*daemon.Client, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(executable, "daemon", "--wakatime-cli", s.cliPath, "--socket", path)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	go cmd.Wait()
+
+	deadline := time.Now().Add(daemonStartTimeout)
+	for {
+		client, err := daemon.Dial(path, s.projectRoot)
+		if err == nil || time.Now().After(deadline) {
+			return client, err
+		}
+		time.Sleep(daemonPollInterval)
+	}
+}
+
+func (s *Server) forwardToDaemon(hb hackatime.Heartbeat) bool {
+	client := s.daemon.Load()
+	if client == nil {
+		return false
+	}
+	if err := client.Send(hb); err != nil {
+		slog.Warn("DaemonSendFailed", "entity", hb.Entity, "error", err)
+		return false
+	}
+	return true
+}
+
+func (s *Server) closeDaemonClient() {
+	if client := s.daemon.Swap(nil); client != nil {
+		client.Close()
+	}
+}
+
+func (s *Server) daemonWorkspaceToday() string {
+	client := s.daemon.Load()
+	if client == nil {
+		return ""
+	}
+	status, err := client.Status()
+	if err != nil {
+		slog.Debug("DaemonStatusFailed", "error", err)
+		return ""
+	}
+	return status.Today
+}
+
+func (s *Server) reportDaemonMetrics() {
+	client := s.daemon.Load()
+	if client == nil {
+		return
+	}
+	if counts, enabled := s.metrics.snapshot(); enabled {
+		if err := client.ReportMetrics(daemon.Counts(counts)); err != nil {
+			slog.Debug("DaemonMetricsFailed", "error", err)
+		}
+	}
+}
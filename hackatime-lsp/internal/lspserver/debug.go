@@ -0,0 +1,93 @@
+package lspserver
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/dashboard"
+)
+
+type debugState struct {
+	ProjectRoot        string       `json:"project_root"`
+	Queued             int          `json:"queued"`
+	Paused             bool         `json:"paused"`
+	Spilled            bool         `json:"spilled"`
+	Timers             int          `json:"timers"`
+	OpenDocuments      int          `json:"open_documents"`
+	PendingLineChanges int          `json:"pending_line_changes"`
+	LastActivity       time.Time    `json:"last_activity"`
+	Goroutines         int          `json:"goroutines"`
+	Limiter            limiterStats `json:"limiter"`
+	Metrics            MetricCounts `json:"metrics"`
+}
+
+func (s *Server) startDebugServer() {
+	if s.debugAddr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/state", s.serveDebugState)
+
+	addr, err := dashboard.LoopbackAddr(s.debugAddr)
+	if err != nil {
+		slog.Error("DebugServerFailed", "addr", s.debugAddr, "error", err)
+		return
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		slog.Error("DebugServerFailed", "addr", s.debugAddr, "error", err)
+		return
+	}
+	slog.Info("DebugServer", "addr", listener.Addr().String())
+
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			slog.Warn("DebugServerStopped", "error", err)
+		}
+	}()
+}
+
+func (s *Server) serveDebugState(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(s.debugState())
+}
+
+func (s *Server) debugState() debugState {
+	state := debugState{
+		ProjectRoot: s.projectRoot,
+		Queued:      s.queue.Len(),
+		Paused:      s.queue.Paused(),
+		Spilled:     s.queue.Spilled(),
+		Timers:      s.scheduler.Len(),
+		Goroutines:  runtime.NumGoroutine(),
+		Limiter:     s.limiter.stats(),
+	}
+	state.Metrics, _ = s.metrics.snapshot()
+
+	s.documentsMutex.Lock()
+	state.OpenDocuments = len(s.openDocuments)
+	s.documentsMutex.Unlock()
+
+	s.lineChangesMutex.Lock()
+	state.PendingLineChanges = len(s.pendingLineChanges)
+	s.lineChangesMutex.Unlock()
+
+	s.activeMutex.Lock()
+	state.LastActivity = s.lastActivity
+	s.activeMutex.Unlock()
+
+	return state
+}
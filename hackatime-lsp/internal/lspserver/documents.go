@@ -0,0 +1,284 @@
+package lspserver
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/heartbeat"
+)
+
+const (
+	positionEncodingUTF8  = "utf-8"
+	positionEncodingUTF16 = "utf-16"
+	positionEncodingUTF32 = "utf-32"
+)
+
+func negotiatePositionEncoding(offered []string) string {
+	for _, preferred := range []string{positionEncodingUTF8, positionEncodingUTF32} {
+		for _, encoding := range offered {
+			if encoding == preferred {
+				return preferred
+			}
+		}
+	}
+	return positionEncodingUTF16
+}
+
+type document struct {
+	entity string
+	text   string
+	lines  int
+}
+
+func countLines(text string) int {
+	return strings.Count(text, "\n") + 1
+}
+
+func (s *Server) openDocument(doc documentRef, text string) {
+	s.documentsMutex.Lock()
+	defer s.documentsMutex.Unlock()
+
+	s.openDocuments[doc.key()] = &document{entity: doc.Entity, text: text, lines: countLines(text)}
+}
+
+func (s *Server) saveCursorPosition(uri string, line, pos int) {
+	s.documentsMutex.Lock()
+	defer s.documentsMutex.Unlock()
+
+	s.lastCursorPos[uri] = pos
+}
+
+func (s *Server) getCursorPosition(uri string) int {
+	s.documentsMutex.Lock()
+	defer s.documentsMutex.Unlock()
+
+	if pos, exists := s.lastCursorPos[uri]; exists {
+		return pos
+	}
+	return 0
+}
+
+func (s *Server) saveDocumentLanguage(uri, languageId string) {
+	s.documentsMutex.Lock()
+	defer s.documentsMutex.Unlock()
+
+	s.documentLanguages[uri] = languageId
+}
+
+func (s *Server) getDocumentLanguage(uri string) string {
+	s.documentsMutex.Lock()
+	defer s.documentsMutex.Unlock()
+
+	return s.documentLanguages[uri]
+}
+
+func (s *Server) markBinaryDocument(uri, text string) {
+	s.documentsMutex.Lock()
+	defer s.documentsMutex.Unlock()
+
+	s.binaryDocuments[uri] = strings.ContainsRune(text, 0)
+}
+
+func (s *Server) isBinaryFile(path string) bool {
+	if heartbeat.HasBinaryExtension(path) {
+		return true
+	}
+
+	s.documentsMutex.Lock()
+	defer s.documentsMutex.Unlock()
+
+	return s.binaryDocuments[path]
+}
+
+func (s *Server) rememberDocument(uri string, doc documentRef) {
+	s.documentsMutex.Lock()
+	defer s.documentsMutex.Unlock()
+
+	s.documentRefs[uri] = doc
+}
+
+func (s *Server) documentForURI(uri string) (documentRef, bool) {
+	s.documentsMutex.Lock()
+	doc, exists := s.documentRefs[uri]
+	s.documentsMutex.Unlock()
+
+	if exists {
+		return doc, true
+	}
+	return s.resolveDocumentURI(uri)
+}
+
+func (s *Server) forgetDocument(uri string) {
+	s.documentsMutex.Lock()
+	defer s.documentsMutex.Unlock()
+
+	delete(s.documentRefs, uri)
+}
+
+func (s *Server) closeDocument(uri string) {
+	s.documentsMutex.Lock()
+	defer s.documentsMutex.Unlock()
+
+	delete(s.openDocuments, uri)
+	delete(s.lastCursorPos, uri)
+	delete(s.documentLanguages, uri)
+	delete(s.binaryDocuments, uri)
+}
+
+func (s *Server) pruneDocumentState() map[string]bool {
+	s.documentsMutex.Lock()
+	defer s.documentsMutex.Unlock()
+
+	open := make(map[string]bool, len(s.openDocuments))
+	entities := make(map[string]bool, len(s.openDocuments))
+	for uri, doc := range s.openDocuments {
+		open[uri] = true
+		entities[doc.entity] = true
+	}
+
+	for uri, doc := range s.documentRefs {
+		if !open[doc.key()] {
+			delete(s.documentRefs, uri)
+		}
+	}
+	for uri := range s.lastCursorPos {
+		if !open[uri] {
+			delete(s.lastCursorPos, uri)
+		}
+	}
+	for uri := range s.documentLanguages {
+		if !open[uri] {
+			delete(s.documentLanguages, uri)
+		}
+	}
+	for uri := range s.binaryDocuments {
+		if !open[uri] {
+			delete(s.binaryDocuments, uri)
+		}
+	}
+	return entities
+}
+
+func (s *Server) isDocumentOpen(uri string) bool {
+	s.documentsMutex.Lock()
+	defer s.documentsMutex.Unlock()
+
+	_, exists := s.openDocuments[uri]
+	return exists
+}
+
+func (s *Server) applyDocumentChanges(uri string, changes []interface{}) int {
+	s.documentsMutex.Lock()
+	defer s.documentsMutex.Unlock()
+
+	doc, exists := s.openDocuments[uri]
+	if !exists {
+		return 0
+	}
+
+	deleted := 0
+
+	for _, change := range changes {
+		switch changeEvent := change.(type) {
+		case protocol.TextDocumentContentChangeEventWhole:
+			doc.text = changeEvent.Text
+			doc.lines = countLines(doc.text)
+		case protocol.TextDocumentContentChangeEvent:
+			if changeEvent.Range == nil {
+				doc.text = changeEvent.Text
+				doc.lines = countLines(doc.text)
+				continue
+			}
+			start := positionOffset(doc.text, changeEvent.Range.Start, s.positionEncoding)
+			end := positionOffset(doc.text, changeEvent.Range.End, s.positionEncoding)
+			if end < start {
+				start, end = end, start
+			}
+			removed := doc.text[start:end]
+			deleted += utf8.RuneCountInString(removed)
+			doc.lines += strings.Count(changeEvent.Text, "\n") - strings.Count(removed, "\n")
+			doc.text = doc.text[:start] + changeEvent.Text + doc.text[end:]
+		}
+	}
+
+	return deleted
+}
+
+func (s *Server) documentLineCount(uri string) (int, bool) {
+	s.documentsMutex.Lock()
+	defer s.documentsMutex.Unlock()
+
+	doc, exists := s.openDocuments[uri]
+	if !exists {
+		return 0, false
+	}
+	return doc.lines, true
+}
+
+func (s *Server) documentSize(doc documentRef) int64 {
+	s.documentsMutex.Lock()
+	open, exists := s.openDocuments[doc.key()]
+	s.documentsMutex.Unlock()
+
+	if exists {
+		return int64(len(open.text))
+	}
+	return heartbeat.FileSize(doc.Entity)
+}
+
+func positionOffset(text string, pos protocol.Position, encoding string) int {
+	offset := 0
+	for line := protocol.UInteger(0); line < pos.Line; line++ {
+		next := strings.IndexByte(text[offset:], '\n')
+		if next < 0 {
+			return len(text)
+		}
+		offset += next + 1
+	}
+
+	lineEnd := strings.IndexByte(text[offset:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(text) - offset
+	}
+	lineText := text[offset : offset+lineEnd]
+
+	return offset + columnByteOffset(lineText, pos.Character, encoding)
+}
+
+func columnByteOffset(lineText string, character protocol.UInteger, encoding string) int {
+	if encoding == positionEncodingUTF8 {
+		if int(character) > len(lineText) {
+			return len(lineText)
+		}
+		return int(character)
+	}
+
+	units := protocol.UInteger(0)
+	for i, r := range lineText {
+		if units >= character {
+			return i
+		}
+		if r >= 0x10000 && encoding == positionEncodingUTF16 {
+			units += 2
+		} else {
+			units++
+		}
+	}
+	return len(lineText)
+}
+
+func (s *Server) characterColumn(uri string, pos protocol.Position) int {
+	s.documentsMutex.Lock()
+	defer s.documentsMutex.Unlock()
+
+	doc, exists := s.openDocuments[uri]
+	if !exists || s.positionEncoding == positionEncodingUTF32 {
+		return int(pos.Character)
+	}
+
+	lineStart := positionOffset(doc.text, protocol.Position{Line: pos.Line}, s.positionEncoding)
+	offset := positionOffset(doc.text, pos, s.positionEncoding)
+	return utf8.RuneCountInString(doc.text[lineStart:offset])
+}
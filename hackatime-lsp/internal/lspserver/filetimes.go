@@ -0,0 +1,164 @@
+package lspserver
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tliron/glsp"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+const methodFileTime = "hackatime/fileTime"
+
+type fileTimes struct {
+	mutex      sync.Mutex
+	path       string
+	timeout    time.Duration
+	lastEntity string
+	lastTime   time.Time
+	saved      map[string]float64
+	pending    map[string]float64
+}
+
+type fileTimesFile struct {
+	Files map[string]float64 `json:"files"`
+}
+
+func newFileTimes(path string, timeout time.Duration) *fileTimes {
+	f := &fileTimes{
+		path:    path,
+		timeout: timeout,
+		pending: make(map[string]float64),
+	}
+	f.saved, _ = f.load()
+	return f
+}
+
+func (f *fileTimes) load() (map[string]float64, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return make(map[string]float64), nil
+		}
+		return make(map[string]float64), err
+	}
+
+	var file fileTimesFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Files == nil {
+		return make(map[string]float64), err
+	}
+	return file.Files, nil
+}
+
+func (f *fileTimes) record(entity string, at time.Time) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.lastEntity != "" {
+		if gap := at.Sub(f.lastTime); gap > 0 && gap < f.timeout {
+			f.pending[f.lastEntity] += gap.Seconds()
+		}
+	}
+	if at.After(f.lastTime) || f.lastEntity == "" {
+		f.lastEntity = entity
+		f.lastTime = at
+	}
+}
+
+func (f *fileTimes) total(entity string) time.Duration {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return time.Duration((f.saved[entity] + f.pending[entity]) * float64(time.Second))
+}
+
+func (f *fileTimes) save() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if len(f.pending) == 0 {
+		return nil
+	}
+
+	files, err := f.load()
+	if err != nil {
+		return err
+	}
+	for entity, seconds := range f.pending {
+		files[entity] += seconds
+	}
+
+	data, err := json.Marshal(fileTimesFile{Files: files})
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(f.path, data); err != nil {
+		return err
+	}
+	f.saved = files
+	f.pending = make(map[string]float64)
+	return nil
+}
+
+func (s *Server) loadFileTimes() {
+	path := config.FileTimesPath()
+	if path == "" || !s.settings.Bool("track_file_time", true) {
+		return
+	}
+
+	timeout := time.Duration(s.settings.Int("keystroke_timeout", defaultKeystrokeTimeoutMinutes)) * time.Minute
+	s.fileTimes = newFileTimes(path, timeout)
+}
+
+func (s *Server) recordFileTime(hb hackatime.Heartbeat) {
+	if s.fileTimes != nil && hb.EntityType == "file" {
+		s.fileTimes.record(hb.Entity, time.UnixMilli(int64(hb.Time*1000)))
+	}
+}
+
+func (s *Server) saveFileTimes() {
+	if s.fileTimes == nil {
+		return
+	}
+	if err := s.fileTimes.save(); err != nil {
+		slog.Warn("FileTimesFailed", "error", err)
+	}
+}
+
+type FileTimeParams struct {
+	URI string `json:"uri"`
+}
+
+type FileTimeResult struct {
+	Entity       string  `json:"entity"`
+	TotalSeconds float64 `json:"total_seconds"`
+	Text         string  `json:"text"`
+}
+
+func (s *Server) handleFileTime(ctx *glsp.Context) (any, error) {
+	var params FileTimeParams
+	if err := json.Unmarshal(ctx.Params, &params); err != nil {
+		return nil, err
+	}
+	if s.fileTimes == nil {
+		return nil, errors.New("track_file_time is off")
+	}
+
+	doc, ok := s.documentForURI(params.URI)
+	if !ok {
+		return nil, nil
+	}
+
+	total := s.fileTimes.total(doc.Entity)
+	return FileTimeResult{
+		Entity:       doc.Entity,
+		TotalSeconds: total.Seconds(),
+		Text:         hackatime.FormatDuration(total),
+	}, nil
+}
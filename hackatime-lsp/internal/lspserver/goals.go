@@ -0,0 +1,105 @@
+package lspserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+const (
+	goalsKey                    = "hackatime/goals"
+	defaultGoalsIntervalMinutes = 15
+	defaultGoalAtRiskHour       = 20
+)
+
+type goalNotifier struct {
+	mutex    sync.Mutex
+	notified map[string]string
+}
+
+func (s *Server) startGoalNotifications(ctx *glsp.Context) {
+	if !s.settings.Bool("goal_notifications", false) {
+		return
+	}
+
+	interval := time.Duration(s.settings.Int("goals_interval", defaultGoalsIntervalMinutes)) * time.Minute
+	notify := ctx.Notify
+	s.whileActive(goalsKey, interval, func() {
+		s.checkGoals(notify)
+	})
+}
+
+func (s *Server) checkGoals(notify glsp.NotifyFunc) {
+	apiKey := config.ApiKey()
+	if apiKey == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), summariesTimeout)
+	defer cancel()
+
+	goals, err := s.newClient(apiKey).Goals(ctx)
+	if err != nil {
+		slog.Debug("GoalsFailed", "result", err)
+		return
+	}
+
+	skip := s.settings.List("goal_notifications_skip")
+	atRiskHour := s.settings.Int("goal_at_risk_hour", defaultGoalAtRiskHour)
+	now := time.Now()
+	for _, goal := range goals {
+		if goal.Delta != "day" || !goal.IsEnabled || goal.IsSnoozed {
+			continue
+		}
+		if slices.Contains(skip, goal.ID) || slices.Contains(skip, goal.Title) {
+			continue
+		}
+		today, ok := goal.Today()
+		if !ok || today.GoalSeconds <= 0 {
+			continue
+		}
+
+		switch {
+		case today.ActualSeconds >= today.GoalSeconds:
+			s.notifyGoal(notify, goal, "reached", protocol.MessageTypeInfo,
+				fmt.Sprintf("Hackatime: goal reached, %s (%s today)", goal.Title, formatSeconds(today.ActualSeconds)))
+		case atRiskHour > 0 && atRiskHour < 24 && now.Hour() >= atRiskHour:
+			s.notifyGoal(notify, goal, "at_risk", protocol.MessageTypeWarning,
+				fmt.Sprintf("Hackatime: %s left to reach %s today", formatSeconds(today.GoalSeconds-today.ActualSeconds), goal.Title))
+		}
+	}
+}
+
+func (s *Server) notifyGoal(notify glsp.NotifyFunc, goal hackatime.Goal, state string, messageType protocol.MessageType, message string) {
+	key := time.Now().Format("2006-01-02") + "/" + state
+
+	s.goals.mutex.Lock()
+	if s.goals.notified == nil {
+		s.goals.notified = make(map[string]string)
+	}
+	if s.goals.notified[goal.ID] == key {
+		s.goals.mutex.Unlock()
+		return
+	}
+	s.goals.notified[goal.ID] = key
+	s.goals.mutex.Unlock()
+
+	slog.Info("Goal", "goal", goal.Title, "state", state)
+	notify(protocol.ServerWindowShowMessage, protocol.ShowMessageParams{
+		Type:    messageType,
+		Message: message,
+	})
+}
+
+func formatSeconds(seconds float64) string {
+	return hackatime.FormatDuration(time.Duration(seconds * float64(time.Second)))
+}
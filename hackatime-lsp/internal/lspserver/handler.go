@@ -0,0 +1,122 @@
+package lspserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/heartbeat"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/logging"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+const (
+	methodAIEdit   = "hackatime/aiEdit"
+	methodActivity = "hackatime/activity"
+)
+
+var activityEntityTypes = map[string]bool{
+	"app":    true,
+	"domain": true,
+}
+
+type customHandlerFunc func(ctx *glsp.Context) (any, error)
+
+type serverHandler struct {
+	protocol.Handler
+	custom map[string]customHandlerFunc
+}
+
+func (h *serverHandler) Handle(ctx *glsp.Context) (r any, validMethod bool, validParams bool, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logging.WriteCrash(ctx.Method, recovered, debug.Stack())
+			r, validMethod, validParams, err = nil, true, true, fmt.Errorf("hackatime: internal error handling %s", ctx.Method)
+		}
+	}()
+
+	if handle, exists := h.custom[ctx.Method]; exists {
+		if !h.Handler.IsInitialized() {
+			return nil, true, true, nil
+		}
+		r, err = handle(ctx)
+		if _, invalid := err.(*json.SyntaxError); invalid {
+			return nil, true, false, err
+		}
+		if _, invalid := err.(*json.UnmarshalTypeError); invalid {
+			return nil, true, false, err
+		}
+		return r, true, true, err
+	}
+	return h.Handler.Handle(ctx)
+}
+
+func recoverPanic(source string) {
+	if recovered := recover(); recovered != nil {
+		logging.WriteCrash(source, recovered, debug.Stack())
+	}
+}
+
+type AIEditParams struct {
+	URI   string `json:"uri"`
+	Lines int    `json:"lines"`
+}
+
+func (s *Server) handleAIEdit(ctx *glsp.Context) (any, error) {
+	var params AIEditParams
+	if err := json.Unmarshal(ctx.Params, &params); err != nil {
+		return nil, err
+	}
+
+	doc, ok := s.documentForURI(params.URI)
+	if !ok || params.Lines <= 0 {
+		return nil, nil
+	}
+
+	s.markAIEdit(doc.Entity, params.Lines)
+	slog.Debug("AIEdit", "entity", doc.Entity, "lines", params.Lines)
+	return nil, nil
+}
+
+type ActivityParams struct {
+	Entity   string `json:"entity"`
+	Type     string `json:"type"`
+	Category string `json:"category"`
+	Project  string `json:"project"`
+	Language string `json:"language"`
+}
+
+func (s *Server) handleActivity(ctx *glsp.Context) (any, error) {
+	var params ActivityParams
+	if err := json.Unmarshal(ctx.Params, &params); err != nil {
+		return nil, err
+	}
+	if params.Entity == "" || !activityEntityTypes[params.Type] {
+		slog.Debug("ActivityIgnored", "entity", params.Entity, "type", params.Type)
+		return nil, nil
+	}
+
+	category := params.Category
+	if category == "" {
+		category = heartbeat.CategoryCoding
+	}
+	hb := hackatime.Heartbeat{
+		Entity:           params.Entity,
+		EntityType:       params.Type,
+		Category:         category,
+		Plugin:           s.plugin,
+		Time:             float64(time.Now().UnixMilli()) / 1000.0,
+		Language:         params.Language,
+		AlternateProject: params.Project,
+	}
+
+	logEvent("Activity", hb)
+	s.markActive(hb)
+	s.throttledHeartbeat(hb)
+	return nil, nil
+}
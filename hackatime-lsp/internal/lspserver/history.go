@@ -0,0 +1,84 @@
+package lspserver
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/dashboard"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+const (
+	defaultHistoryDays             = 14
+	defaultKeystrokeTimeoutMinutes = 15
+)
+
+func (s *Server) loadHistory() {
+	path := config.HistoryFilePath()
+	if path == "" || !s.settings.Bool("local_history", true) {
+		return
+	}
+
+	history := hackatime.NewStore(path)
+	cutoff := time.Now().AddDate(0, 0, -s.settings.Int("local_history_days", defaultHistoryDays))
+	if err := history.Prune(func(hb hackatime.Heartbeat) bool {
+		return hb.Time >= float64(cutoff.Unix())
+	}); err != nil {
+		slog.Warn("HistoryPruneFailed", "error", err)
+	}
+	s.history = history
+}
+
+func (s *Server) recordHistory(heartbeats []hackatime.Heartbeat) {
+	if s.history == nil {
+		return
+	}
+	if err := s.history.Append(heartbeats); err != nil {
+		slog.Warn("HistoryFailed", "error", err)
+	}
+}
+
+func (s *Server) localTotal(since time.Time) (time.Duration, bool) {
+	if s.history == nil {
+		return 0, false
+	}
+
+	heartbeats, err := s.history.Read(func(hb hackatime.Heartbeat) bool {
+		return hb.Time >= float64(since.Unix())
+	})
+	if err != nil {
+		slog.Debug("HistoryReadFailed", "error", err)
+		return 0, false
+	}
+
+	timeout := time.Duration(s.settings.Int("keystroke_timeout", defaultKeystrokeTimeoutMinutes)) * time.Minute
+	return hackatime.TotalDuration(heartbeats, timeout), true
+}
+
+func (s *Server) localToday() string {
+	now := time.Now()
+	total, ok := s.localTotal(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()))
+	if !ok {
+		return ""
+	}
+	return hackatime.FormatDuration(total)
+}
+
+func (s *Server) startDashboard() {
+	if s.dashboardAddr == "" {
+		return
+	}
+	if s.history == nil {
+		slog.Warn("DashboardDisabled", "result", "local_history is off")
+		return
+	}
+
+	board := &dashboard.Dashboard{
+		History: s.history,
+		Timeout: time.Duration(s.settings.Int("keystroke_timeout", defaultKeystrokeTimeoutMinutes)) * time.Minute,
+	}
+	if err := board.Serve(s.dashboardAddr); err != nil {
+		slog.Error("DashboardFailed", "addr", s.dashboardAddr, "error", err)
+	}
+}
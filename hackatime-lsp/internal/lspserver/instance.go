@@ -0,0 +1,32 @@
+package lspserver
+
+import (
+	"log/slog"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/instance"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+func (s *Server) loadInstanceRegistry() {
+	dir := config.InstancesDir()
+	if dir == "" || !s.settings.Bool("instance_lock", true) {
+		return
+	}
+	s.instances = instance.Open(dir, s.projectRoot, s.throttle.Interval())
+}
+
+func (s *Server) ownsHeartbeat(hb hackatime.Heartbeat) bool {
+	if s.instances == nil || s.instances.Claim(hb.Entity, hb.IsWrite) {
+		return true
+	}
+	slog.Debug("HeartbeatOwnedElsewhere", "entity", hb.Entity, "project", hb.AlternateProject)
+	s.metrics.add(func(counts *MetricCounts) { counts.Deduped++ })
+	return false
+}
+
+func (s *Server) releaseInstanceRegistry() {
+	if s.instances != nil {
+		s.instances.Release()
+	}
+}
@@ -0,0 +1,111 @@
+package lspserver
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+const defaultIdleTimeoutMinutes = 15
+
+const keepAliveSlack = 10 * time.Second
+
+func (s *Server) touchActivity() {
+	s.activeMutex.Lock()
+	s.lastActivity = time.Now()
+	s.activeMutex.Unlock()
+
+	s.backends.Resume()
+	if s.queue.Resume() {
+		slog.Info("Active", "queued", s.queue.Len())
+		s.writeStatusFile(true)
+	}
+}
+
+func (s *Server) markActive(hb hackatime.Heartbeat) {
+	s.activeMutex.Lock()
+	s.activeHeartbeat = hb
+	s.activeHeartbeat.IsWrite = false
+	s.activeHeartbeat = applyLineChanges(s.activeHeartbeat, lineChange{})
+	s.activeMutex.Unlock()
+
+	s.touchActivity()
+}
+
+func idleTimeout() time.Duration {
+	return time.Duration(config.Int("idle_timeout", defaultIdleTimeoutMinutes)) * time.Minute
+}
+
+func (s *Server) isIdle() bool {
+	s.activeMutex.Lock()
+	defer s.activeMutex.Unlock()
+
+	return !s.lastActivity.IsZero() && time.Since(s.lastActivity) >= idleTimeout()
+}
+
+func (s *Server) startKeepAlive() {
+	s.keepAliveOnce.Do(func() {
+		ticker := time.NewTicker(hackatime.Interval + keepAliveSlack)
+		s.activeMutex.Lock()
+		s.keepAlive = ticker
+		s.activeMutex.Unlock()
+
+		go func() {
+			for range ticker.C {
+				s.keepAliveTick()
+			}
+		}()
+	})
+}
+
+func (s *Server) whileActive(key string, interval time.Duration, f func()) {
+	var run func()
+	run = func() {
+		defer s.scheduler.Schedule(key, interval, run)
+		defer recoverPanic(key)
+
+		if !s.isIdle() {
+			f()
+		}
+	}
+	go run()
+}
+
+func (s *Server) keepAliveTick() {
+	defer recoverPanic("keepalive")
+
+	s.pruneLineChanges(s.pruneDocumentState())
+	s.metrics.logPeriodically()
+	s.reportDaemonMetrics()
+	s.saveFileTimes()
+
+	if s.isIdle() {
+		s.backends.Pause()
+		if s.queue.Pause() {
+			slog.Info("Idle", "queued", s.queue.Len())
+			s.writeStatusFile(true)
+		}
+		return
+	}
+	s.sendKeepAlive()
+	s.writeStatusFile(true)
+}
+
+func (s *Server) sendKeepAlive() {
+	s.activeMutex.Lock()
+	hb := s.activeHeartbeat
+	s.activeMutex.Unlock()
+
+	if hb.Entity == "" {
+		return
+	}
+	if !hb.IsUnsaved && !s.isDocumentOpen(hb.Entity) {
+		return
+	}
+
+	hb.Time = float64(time.Now().UnixMilli()) / 1000.0
+	logEvent("KeepAlive", hb)
+	s.throttledHeartbeat(hb)
+}
@@ -0,0 +1,57 @@
+package lspserver
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+const (
+	leaderboardKey                    = "hackatime/leaderboard"
+	defaultLeaderboardIntervalMinutes = 30
+)
+
+func (s *Server) startLeaderboard() {
+	if !s.settings.Bool("show_leaderboard", false) {
+		return
+	}
+
+	period := s.settings.String("leaderboard_period")
+	if period != hackatime.LeaderboardWeekly {
+		period = hackatime.LeaderboardDaily
+	}
+	interval := time.Duration(s.settings.Int("leaderboard_interval", defaultLeaderboardIntervalMinutes)) * time.Minute
+	s.whileActive(leaderboardKey, interval, func() {
+		s.refreshLeaderboard(period)
+	})
+}
+
+func (s *Server) refreshLeaderboard(period string) {
+	apiKey := config.ApiKey()
+	if apiKey == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), summariesTimeout)
+	defer cancel()
+
+	_, rank, err := s.newClient(apiKey).LeaderboardRank(ctx, period)
+	if err != nil {
+		slog.Debug("LeaderboardFailed", "result", err)
+		return
+	}
+
+	s.statusMutex.Lock()
+	s.leaderboard = rank
+	s.statusMutex.Unlock()
+}
+
+func (s *Server) leaderboardRank() *hackatime.LeaderboardRank {
+	s.statusMutex.Lock()
+	defer s.statusMutex.Unlock()
+
+	return s.leaderboard
+}
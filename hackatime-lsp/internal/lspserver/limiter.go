@@ -0,0 +1,63 @@
+package lspserver
+
+import "sync"
+
+const defaultMaxConcurrency = 8
+
+type limiter struct {
+	slots chan struct{}
+
+	mutex    sync.Mutex
+	inFlight int
+	peak     int
+	waited   int
+}
+
+type limiterStats struct {
+	Limit    int `json:"limit"`
+	InFlight int `json:"in_flight"`
+	Peak     int `json:"peak"`
+	Waited   int `json:"waited"`
+}
+
+func newLimiter(limit int) *limiter {
+	return &limiter{slots: make(chan struct{}, limit)}
+}
+
+func (l *limiter) Go(f func()) {
+	select {
+	case l.slots <- struct{}{}:
+	default:
+		l.mutex.Lock()
+		l.waited++
+		l.mutex.Unlock()
+		l.slots <- struct{}{}
+	}
+
+	l.mutex.Lock()
+	l.inFlight++
+	l.peak = max(l.peak, l.inFlight)
+	l.mutex.Unlock()
+
+	go func() {
+		defer func() {
+			l.mutex.Lock()
+			l.inFlight--
+			l.mutex.Unlock()
+			<-l.slots
+		}()
+		f()
+	}()
+}
+
+func (l *limiter) stats() limiterStats {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return limiterStats{
+		Limit:    cap(l.slots),
+		InFlight: l.inFlight,
+		Peak:     l.peak,
+		Waited:   l.waited,
+	}
+}
@@ -0,0 +1,312 @@
+package lspserver
+
+import (
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/heartbeat"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/version"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+var fileScheme = "file"
+
+func (s *Server) handler() *serverHandler {
+	handler := &serverHandler{
+		custom: map[string]customHandlerFunc{
+			methodAIEdit:    s.handleAIEdit,
+			methodActivity:  s.handleActivity,
+			methodStatus:    s.handleStatus,
+			methodSummaries: s.handleSummaries,
+			methodFileTime:  s.handleFileTime,
+		},
+	}
+	handler.Handler = protocol.Handler{
+		Initialize:            s.initialize,
+		Initialized:           s.initialized,
+		Shutdown:              s.shutdown,
+		TextDocumentDidOpen:   s.didOpen,
+		TextDocumentDidClose:  s.didClose,
+		TextDocumentDidChange: s.didChange,
+		TextDocumentDidSave:   s.didSave,
+
+		WorkspaceDidRenameFiles: s.didRenameFiles,
+	}
+	return handler
+}
+
+func (s *Server) initialize(ctx *glsp.Context, params *protocol.InitializeParams) (any, error) {
+	if options, ok := params.InitializationOptions.(map[string]interface{}); ok {
+		s.settings = config.Settings{InitOptions: options}
+	}
+	if params.RootURI != nil {
+		s.setWorkspaceRoot(cleanFileURI(*params.RootURI))
+	} else if params.RootPath != nil {
+		s.setWorkspaceRoot(filepath.Clean(*params.RootPath))
+	}
+
+	if params.ClientInfo != nil {
+		clientVersion := ""
+		if params.ClientInfo.Version != nil {
+			clientVersion = *params.ClientInfo.Version
+		}
+		s.plugin = version.Plugin(params.ClientInfo.Name, clientVersion)
+	}
+	if params.Capabilities.Window != nil && params.Capabilities.Window.ShowDocument != nil {
+		s.clientSupportsShowDocument = params.Capabilities.Window.ShowDocument.Support
+	}
+
+	var clientParams struct {
+		Capabilities struct {
+			General struct {
+				PositionEncodings []string `json:"positionEncodings"`
+			} `json:"general"`
+		} `json:"capabilities"`
+	}
+	if err := json.Unmarshal(ctx.Params, &clientParams); err == nil {
+		s.positionEncoding = negotiatePositionEncoding(clientParams.Capabilities.General.PositionEncodings)
+	}
+
+	s.applyLogSettings()
+	s.loadQueueSettings()
+	s.startDaemonClient()
+	s.loadInstanceRegistry()
+	s.loadHistory()
+	s.loadStatusFile()
+	s.loadFileTimes()
+	s.metrics.setEnabled(s.settings.Bool("metrics", false))
+
+	capabilities := ServerCapabilities{
+		ServerCapabilities: protocol.ServerCapabilities{
+			TextDocumentSync: protocol.TextDocumentSyncKindIncremental,
+			Workspace: &protocol.ServerCapabilitiesWorkspace{
+				FileOperations: &protocol.ServerCapabilitiesWorkspaceFileOperations{
+					DidRename: &protocol.FileOperationRegistrationOptions{
+						Filters: []protocol.FileOperationFilter{{Scheme: &fileScheme, Pattern: protocol.FileOperationPattern{Glob: "**/*"}}},
+					},
+				},
+			},
+			Experimental: map[string]interface{}{
+				"hackatimeAiEdit":    true,
+				"hackatimeStatus":    true,
+				"hackatimeSummaries": true,
+				"hackatimeFileTime":  true,
+				"hackatimeToday":     s.settings.Bool("show_today", false),
+			},
+		},
+		PositionEncoding: s.positionEncoding,
+	}
+	serverInfo := &protocol.InitializeResultServerInfo{Name: version.Name, Version: &version.Version}
+	return InitializeResult{Capabilities: capabilities, ServerInfo: serverInfo}, nil
+}
+
+func (s *Server) initialized(ctx *glsp.Context, params *protocol.InitializedParams) error {
+	s.forwardLogs(ctx)
+	s.startKeepAlive()
+	s.writeStatusFile(true)
+	s.startToday(ctx)
+	s.startGoalNotifications(ctx)
+	s.startLeaderboard()
+	s.startDashboard()
+	s.startUpdateCheck(ctx)
+	if config.ApiKey() == "" {
+		go s.runOnboarding(ctx)
+		return nil
+	}
+	s.reportConfigProblems(ctx)
+	return nil
+}
+
+func (s *Server) shutdown(ctx *glsp.Context) error {
+	goroutines := s.limiter.stats()
+	slog.Info("Shutdown", "queued", s.queue.Len(), "goroutines_peak", goroutines.Peak, "goroutine_waits", goroutines.Waited)
+	s.Close()
+	return nil
+}
+
+func (s *Server) didOpen(ctx *glsp.Context, params *protocol.DidOpenTextDocumentParams) error {
+	doc, ok := s.resolveDocumentURI(params.TextDocument.URI)
+	if !ok {
+		return nil
+	}
+	uri, key := doc.Entity, doc.key()
+	s.rememberDocument(params.TextDocument.URI, doc)
+	s.touchActivity()
+	s.saveDocumentLanguage(key, params.TextDocument.LanguageID)
+	s.markBinaryDocument(key, params.TextDocument.Text)
+	s.openDocument(doc, params.TextDocument.Text)
+
+	if !s.settings.Bool("track_browsing", true) {
+		return nil
+	}
+
+	largeFile := heartbeat.LargeFileAction(int64(len(params.TextDocument.Text)))
+	if largeFile == heartbeat.FileActionSkip {
+		return nil
+	}
+
+	lines, _ := s.documentLineCount(key)
+	if largeFile != "" {
+		lines = 0
+	}
+
+	hb := hackatime.Heartbeat{
+		Entity:           uri,
+		EntityType:       "file",
+		Category:         heartbeat.CategoryBrowsing,
+		Plugin:           s.plugin,
+		Time:             float64(time.Now().UnixMilli()) / 1000.0,
+		LineNumber:       1,
+		Lines:            lines,
+		Language:         s.getDocumentLanguage(key),
+		IsUnsaved:        doc.IsUnsaved,
+		LocalFile:        doc.LocalFile,
+		AlternateProject: doc.Project,
+		ProjectFolder:    doc.ProjectFolder,
+	}
+
+	logEvent("TextDocumentDidOpen", hb)
+	s.markActive(hb)
+	s.throttledHeartbeat(hb)
+	return nil
+}
+
+func (s *Server) didClose(ctx *glsp.Context, params *protocol.DidCloseTextDocumentParams) error {
+	s.touchActivity()
+	if doc, ok := s.documentForURI(params.TextDocument.URI); ok {
+		s.forgetDocument(params.TextDocument.URI)
+		s.closeDocument(doc.key())
+		forgetPathCase(doc.Entity)
+		if doc.Cell == "" {
+			s.throttle.Forget(doc.Entity)
+		}
+	}
+	return nil
+}
+
+func (s *Server) didRenameFiles(ctx *glsp.Context, params *protocol.RenameFilesParams) error {
+	for _, file := range params.Files {
+		forgetPathCase(cleanFileURI(file.OldURI))
+		forgetPathCase(cleanFileURI(file.NewURI))
+	}
+	return nil
+}
+
+func (s *Server) didChange(ctx *glsp.Context, params *protocol.DidChangeTextDocumentParams) error {
+	doc, ok := s.documentForURI(params.TextDocument.URI)
+	if !ok {
+		return nil
+	}
+	uri, key := doc.Entity, doc.key()
+
+	largeFile := heartbeat.LargeFileAction(s.documentSize(doc))
+	if largeFile == heartbeat.FileActionSkip {
+		return nil
+	}
+
+	lines := 1
+	lineNumber := 1
+	cursorPos := 0
+
+	if len(params.ContentChanges) > 0 {
+		change := params.ContentChanges[0]
+
+		if changeEvent, ok := change.(protocol.TextDocumentContentChangeEvent); ok {
+			if changeEvent.Range != nil {
+				lineNumber = int(changeEvent.Range.Start.Line) + 1
+				cursorPos = s.characterColumn(key, changeEvent.Range.Start)
+			}
+			if changeEvent.Text != "" && largeFile == "" {
+				lines = countLines(changeEvent.Text)
+			}
+		}
+	}
+
+	s.saveCursorPosition(key, lineNumber, cursorPos)
+	s.recordLineChanges(uri, params.ContentChanges)
+	deleted := s.applyDocumentChanges(key, params.ContentChanges)
+	s.recordCharChanges(uri, params.ContentChanges, deleted)
+	if count, exists := s.documentLineCount(key); exists {
+		lines = count
+	}
+	if largeFile != "" {
+		lines = 0
+	}
+
+	hb := hackatime.Heartbeat{
+		Entity:           uri,
+		EntityType:       "file",
+		Category:         heartbeat.DetectCategory(uri, s.documentRoot(doc), s.settings),
+		Plugin:           s.plugin,
+		Time:             float64(time.Now().UnixMilli()) / 1000.0,
+		LineNumber:       lineNumber,
+		CursorPos:        cursorPos,
+		Lines:            lines,
+		Language:         s.getDocumentLanguage(key),
+		IsUnsaved:        doc.IsUnsaved,
+		LocalFile:        doc.LocalFile,
+		AlternateProject: doc.Project,
+		ProjectFolder:    doc.ProjectFolder,
+	}
+
+	logEvent("TextDocumentDidChange", hb)
+	s.markActive(hb)
+	s.throttledHeartbeat(hb)
+	return nil
+}
+
+func (s *Server) didSave(ctx *glsp.Context, params *protocol.DidSaveTextDocumentParams) error {
+	doc, ok := s.documentForURI(params.TextDocument.URI)
+	if !ok {
+		return nil
+	}
+	uri, key := doc.Entity, doc.key()
+
+	size := s.documentSize(doc)
+	if params.Text != nil {
+		size = int64(len(*params.Text))
+	}
+
+	largeFile := heartbeat.LargeFileAction(size)
+	if largeFile == heartbeat.FileActionSkip {
+		return nil
+	}
+
+	lines := 1
+	if largeFile != "" {
+		lines = 0
+	} else if params.Text != nil {
+		s.openDocument(doc, *params.Text)
+		lines, _ = s.documentLineCount(key)
+	} else if count, exists := s.documentLineCount(key); exists {
+		lines = count
+	}
+
+	hb := hackatime.Heartbeat{
+		Entity:           uri,
+		EntityType:       "file",
+		Category:         heartbeat.DetectCategory(uri, s.documentRoot(doc), s.settings),
+		Plugin:           s.plugin,
+		Time:             float64(time.Now().UnixMilli()) / 1000.0,
+		LineNumber:       1,
+		Lines:            lines,
+		CursorPos:        s.getCursorPosition(key),
+		IsWrite:          true,
+		Language:         s.getDocumentLanguage(key),
+		IsUnsaved:        doc.IsUnsaved,
+		LocalFile:        doc.LocalFile,
+		AlternateProject: doc.Project,
+		ProjectFolder:    doc.ProjectFolder,
+	}
+
+	logEvent("TextDocumentDidSave", hb)
+	s.markActive(hb)
+	s.throttledHeartbeat(hb)
+	return nil
+}
@@ -0,0 +1,71 @@
+package lspserver
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const metricsLogInterval = 10 * time.Minute
+
+type metrics struct {
+	mutex     sync.Mutex
+	enabled   bool
+	counts    MetricCounts
+	lastLog   time.Time
+	lastCount MetricCounts
+}
+
+type MetricCounts struct {
+	Queued    int `json:"queued"`
+	Sent      int `json:"sent"`
+	Failed    int `json:"failed"`
+	Deduped   int `json:"deduped"`
+	Throttled int `json:"throttled"`
+	Dropped   int `json:"dropped"`
+	Timeouts  int `json:"timeouts"`
+}
+
+func (m *metrics) setEnabled(enabled bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.enabled = enabled
+	m.lastLog = time.Now()
+}
+
+func (m *metrics) add(update func(counts *MetricCounts)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.enabled {
+		update(&m.counts)
+	}
+}
+
+func (m *metrics) snapshot() (MetricCounts, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.counts, m.enabled
+}
+
+func (m *metrics) logPeriodically() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if !m.enabled || time.Since(m.lastLog) < metricsLogInterval || m.counts == m.lastCount {
+		return
+	}
+	slog.Info("Metrics",
+		"queued", m.counts.Queued,
+		"sent", m.counts.Sent,
+		"failed", m.counts.Failed,
+		"deduped", m.counts.Deduped,
+		"throttled", m.counts.Throttled,
+		"dropped", m.counts.Dropped,
+		"timeouts", m.counts.Timeouts,
+	)
+	m.lastLog = time.Now()
+	m.lastCount = m.counts
+}
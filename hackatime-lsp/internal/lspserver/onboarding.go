@@ -0,0 +1,94 @@
+package lspserver
+
+import (
+	"log/slog"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+)
+
+const (
+	setupUrl           = "https://hackatime.hackclub.com/my/wakatime_setup"
+	actionOpenSetup    = "Open Hackatime setup"
+	actionEnterApiKey  = "Enter API key"
+	onboardingKeyField = "api_key"
+)
+
+func (s *Server) runOnboarding(ctx *glsp.Context) {
+	defer recoverPanic("onboarding")
+	if apiKey := s.settings.InitOption(onboardingKeyField); apiKey != "" {
+		if err := config.ValidateApiKey(apiKey); err == nil {
+			s.saveOnboardingKey(ctx, apiKey)
+			return
+		}
+	}
+
+	var choice *protocol.MessageActionItem
+	ctx.Call(protocol.ServerWindowShowMessageRequest, protocol.ShowMessageRequestParams{
+		Type:    protocol.MessageTypeWarning,
+		Message: "Hackatime isn't set up yet, so your coding time isn't being tracked.",
+		Actions: []protocol.MessageActionItem{
+			{Title: actionOpenSetup},
+			{Title: actionEnterApiKey},
+		},
+	}, &choice)
+
+	if choice == nil {
+		slog.Info("OnboardingDismissed")
+		return
+	}
+
+	slog.Info("OnboardingChoice", "choice", choice.Title)
+
+	switch choice.Title {
+	case actionOpenSetup:
+		s.showDocument(ctx, setupUrl, true)
+	case actionEnterApiKey:
+		if err := config.EnsureFile(); err != nil {
+			slog.Error("OnboardingFailed", "error", err)
+		}
+		if !s.clientSupportsShowDocument {
+			ctx.Notify(protocol.ServerWindowShowMessage, protocol.ShowMessageParams{
+				Type:    protocol.MessageTypeInfo,
+				Message: "Hackatime: add api_key = <your key> under [hackatime] in " + config.FilePath(),
+			})
+			return
+		}
+		s.showDocument(ctx, fileURI(config.FilePath()), false)
+	}
+}
+
+func (s *Server) saveOnboardingKey(ctx *glsp.Context, apiKey string) {
+	if err := config.Set("hackatime", onboardingKeyField, apiKey); err != nil {
+		slog.Error("OnboardingFailed", "error", err)
+		ctx.Notify(protocol.ServerWindowShowMessage, protocol.ShowMessageParams{
+			Type:    protocol.MessageTypeError,
+			Message: "Hackatime: couldn't save your API key: " + err.Error(),
+		})
+		return
+	}
+
+	slog.Info("OnboardingKeySaved")
+	ctx.Notify(protocol.ServerWindowShowMessage, protocol.ShowMessageParams{
+		Type:    protocol.MessageTypeInfo,
+		Message: "Hackatime: API key saved, tracking is on!",
+	})
+}
+
+func (s *Server) showDocument(ctx *glsp.Context, uri string, external bool) {
+	if !s.clientSupportsShowDocument {
+		ctx.Notify(protocol.ServerWindowShowMessage, protocol.ShowMessageParams{
+			Type:    protocol.MessageTypeInfo,
+			Message: "Hackatime: open " + uri,
+		})
+		return
+	}
+
+	var result protocol.ShowDocumentResult
+	ctx.Call(protocol.ServerWindowShowDocument, protocol.ShowDocumentParams{
+		URI:      uri,
+		External: &external,
+	}, &result)
+}
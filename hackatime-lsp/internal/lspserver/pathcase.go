@@ -0,0 +1,119 @@
+package lspserver
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+const maxPathCaseDirs = 256
+
+var (
+	pathCaseDirs      = make(map[string]map[string]string)
+	pathCaseDirsMutex sync.Mutex
+)
+
+func (s *Server) canonicalPath(path string) string {
+	path = s.resolveSymlinks(path)
+	caseInsensitive := runtime.GOOS == "darwin" || runtime.GOOS == "windows"
+	if !s.settings.Bool("normalize_path_case", caseInsensitive) || !filepath.IsAbs(path) || s.projectRoot == "" || s.networkProject {
+		return path
+	}
+	return normalizePathCase(s.projectRoot, path)
+}
+
+func normalizePathCase(root, path string) string {
+	rel, ok := cutRootFold(root, path)
+	if !ok {
+		return path
+	}
+
+	current := root
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, dirEntryName(current, part))
+	}
+	return current
+}
+
+func cutRootFold(root, path string) (string, bool) {
+	if len(path) <= len(root) || !strings.EqualFold(path[:len(root)], root) {
+		return "", false
+	}
+	rel := path[len(root):]
+	if !strings.HasSuffix(root, string(filepath.Separator)) {
+		if rel[0] != filepath.Separator {
+			return "", false
+		}
+		rel = rel[1:]
+	}
+	return rel, rel != ""
+}
+
+func dirEntryName(dir, name string) string {
+	pathCaseDirsMutex.Lock()
+	names, exists := pathCaseDirs[dir]
+	pathCaseDirsMutex.Unlock()
+
+	if exists {
+		if match, found := lookupEntryName(names, name); found {
+			return match
+		}
+	}
+
+	names = readDirNames(dir)
+	if names == nil {
+		return name
+	}
+	pathCaseDirsMutex.Lock()
+	if len(pathCaseDirs) >= maxPathCaseDirs {
+		clear(pathCaseDirs)
+	}
+	pathCaseDirs[dir] = names
+	pathCaseDirsMutex.Unlock()
+
+	if match, found := lookupEntryName(names, name); found {
+		return match
+	}
+	return name
+}
+
+func lookupEntryName(names map[string]string, name string) (string, bool) {
+	if match, exists := names[name]; exists {
+		return match, true
+	}
+	match, exists := names[strings.ToLower(name)]
+	return match, exists
+}
+
+func readDirNames(dir string) map[string]string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	names := make(map[string]string, len(entries)*2)
+	for _, entry := range entries {
+		names[entry.Name()] = entry.Name()
+	}
+	for _, entry := range entries {
+		if lower := strings.ToLower(entry.Name()); names[lower] == "" {
+			names[lower] = entry.Name()
+		}
+	}
+	return names
+}
+
+func forgetPathCase(path string) {
+	pathCaseDirsMutex.Lock()
+	defer pathCaseDirsMutex.Unlock()
+
+	delete(pathCaseDirs, filepath.Dir(path))
+	prefix := strings.ToLower(path)
+	for dir := range pathCaseDirs {
+		if lower := strings.ToLower(dir); lower == prefix || strings.HasPrefix(lower, prefix+string(filepath.Separator)) {
+			delete(pathCaseDirs, dir)
+		}
+	}
+}
@@ -0,0 +1,480 @@
+package lspserver
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/tliron/glsp/server"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/backend"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/daemon"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/heartbeat"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/instance"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/logging"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/version"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+const (
+	mockCliName = "wakatime-cli"
+
+	minBatchInterval = 5 * time.Second
+	maxBatchInterval = time.Hour
+)
+
+type Options struct {
+	CliPath       string
+	MockCLI       bool
+	LogOptions    logging.Options
+	DebugAddr     string
+	DashboardAddr string
+	BatchInterval time.Duration
+}
+
+type Server struct {
+	runner        hackatime.Runner
+	cliPath       string
+	mockCLI       bool
+	logOptions    logging.Options
+	debugAddr     string
+	dashboardAddr string
+	batchInterval time.Duration
+	startedAt     time.Time
+
+	projectRoot                string
+	projectFolder              string
+	networkProject             bool
+	settings                   config.Settings
+	clientSupportsShowDocument bool
+	positionEncoding           string
+	plugin                     string
+
+	queue     *hackatime.Queue
+	history   *hackatime.Store
+	fileTimes *fileTimes
+	throttle  *hackatime.Throttle
+	scheduler *hackatime.Scheduler
+	limiter   *limiter
+	daemon    atomic.Pointer[daemon.Client]
+	instances *instance.Registry
+	backends  backend.Backends
+
+	httpClientOnce sync.Once
+	httpClient     *http.Client
+
+	documentsMutex    sync.Mutex
+	openDocuments     map[string]*document
+	documentRefs      map[string]documentRef
+	documentLanguages map[string]string
+	binaryDocuments   map[string]bool
+	lastCursorPos     map[string]int
+
+	lineChangesMutex   sync.Mutex
+	pendingLineChanges map[string]lineChange
+	aiEditMarkers      map[string]aiEditMarker
+	aiEditsReported    bool
+
+	activeMutex     sync.Mutex
+	activeHeartbeat hackatime.Heartbeat
+	lastActivity    time.Time
+	keepAliveOnce   sync.Once
+	keepAlive       *time.Ticker
+
+	statusMutex sync.Mutex
+	today       string
+	leaderboard *hackatime.LeaderboardRank
+	status      statusTracker
+	goals       goalNotifier
+
+	closeOnce    sync.Once
+	closed       atomic.Bool
+	sendStats    sendStats
+	metrics      metrics
+	backpressure backpressure
+}
+
+func New(opts Options) *Server {
+	cliPath := opts.CliPath
+	var runner hackatime.Runner = hackatime.ExecRunner{}
+	if opts.MockCLI {
+		runner = &hackatime.MockRunner{Record: logMockCall}
+		if cliPath == "" {
+			cliPath = mockCliName
+		}
+	}
+
+	s := &Server{
+		runner:             runner,
+		cliPath:            cliPath,
+		mockCLI:            opts.MockCLI,
+		logOptions:         opts.LogOptions,
+		debugAddr:          opts.DebugAddr,
+		dashboardAddr:      opts.DashboardAddr,
+		batchInterval:      opts.BatchInterval,
+		startedAt:          time.Now(),
+		positionEncoding:   positionEncodingUTF16,
+		plugin:             version.Plugin("", ""),
+		throttle:           hackatime.NewThrottle(),
+		scheduler:          hackatime.NewScheduler(),
+		limiter:            newLimiter(config.Int("max_concurrency", defaultMaxConcurrency)),
+		openDocuments:      make(map[string]*document),
+		documentRefs:       make(map[string]documentRef),
+		documentLanguages:  make(map[string]string),
+		binaryDocuments:    make(map[string]bool),
+		lastCursorPos:      make(map[string]int),
+		pendingLineChanges: make(map[string]lineChange),
+		aiEditMarkers:      make(map[string]aiEditMarker),
+	}
+	cliSend := hackatime.NewCLISender(runner, cliPath, s.cliOptions)
+	apiSend := hackatime.NewAPISender(s.apiClient)
+	send := func(heartbeats []hackatime.Heartbeat) error {
+		if s.directAPI() {
+			return apiSend(heartbeats)
+		}
+		return cliSend(heartbeats)
+	}
+	s.queue = hackatime.NewQueue(s.loggedSender(hackatime.PerAccount(send, s.accountSender(send))))
+	s.queue.OnConnectivity(s.logConnectivity)
+	return s
+}
+
+func (s *Server) RunStdio() error {
+	s.startDebugServer()
+	s.closeOnSignal(nil)
+
+	err := server.NewServer(s.handler(), version.Name, false).RunStdio()
+	s.Close()
+	return err
+}
+
+func (s *Server) closeOnSignal(cleanup func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		slog.Info("Signal", "signal", sig.String(), "queued", s.queue.Len())
+		s.Close()
+		if cleanup != nil {
+			cleanup()
+		}
+		os.Exit(0)
+	}()
+}
+
+func (s *Server) Close() {
+	s.closeOnce.Do(func() {
+		s.closed.Store(true)
+		s.activeMutex.Lock()
+		if s.keepAlive != nil {
+			s.keepAlive.Stop()
+		}
+		s.activeMutex.Unlock()
+
+		s.scheduler.Stop()
+		if err := s.queue.Close(); err != nil {
+			slog.Error("QueueCloseFailed", "error", err)
+		}
+		s.backends.Close()
+		s.closeDaemonClient()
+		s.releaseInstanceRegistry()
+		s.saveFileTimes()
+		s.writeStatusFile(false)
+	})
+}
+
+func (s *Server) setWorkspaceRoot(root string) {
+	root = s.canonicalPath(root)
+	s.projectRoot = root
+	s.networkProject = heartbeat.IsNetworkPath(root)
+	s.projectFolder = root
+}
+
+func (s *Server) applyLogSettings() {
+	opts := s.logOptions
+	if file := s.settings.InitOption("log_file"); file != "" {
+		opts.File = file
+	}
+	if level := s.settings.InitOption("log_level"); level != "" {
+		opts.Level = level
+	}
+	opts.Disabled = s.settings.Bool("no_log", opts.Disabled)
+
+	if err := logging.Setup(opts); err != nil {
+		slog.Warn("LogSettingsInvalid", "error", err)
+	}
+}
+
+func (s *Server) loadQueueSettings() {
+	batchInterval := s.resolveBatchInterval()
+	s.queue.Configure(batchInterval, config.Int("queue_size", hackatime.DefaultQueueSize))
+
+	if path := config.QueueFilePath(); path != "" {
+		s.queue.SpillTo(hackatime.NewStore(path), config.Int("queue_memory_limit", hackatime.DefaultMemoryLimit))
+	}
+	if s.backends == nil {
+		s.backends = backend.Open(batchInterval, s.backendSender)
+	}
+}
+
+func (s *Server) resolveBatchInterval() time.Duration {
+	fallback := s.batchInterval
+	if fallback <= 0 {
+		fallback = hackatime.DefaultBatchInterval
+	}
+
+	batchInterval := time.Duration(s.settings.Int("batch_interval", int(fallback/time.Second))) * time.Second
+	if clamped := min(max(batchInterval, minBatchInterval), maxBatchInterval); clamped != batchInterval {
+		slog.Warn("BatchIntervalClamped", "requested", batchInterval.String(), "used", clamped.String())
+		batchInterval = clamped
+	}
+	return batchInterval
+}
+
+func (s *Server) newClient(apiKey string) *hackatime.Client {
+	return s.newClientFor(config.ApiUrl(), apiKey)
+}
+
+func (s *Server) newClientFor(apiUrl, apiKey string) *hackatime.Client {
+	client := hackatime.NewClient(apiUrl, apiKey)
+	client.UserAgent = s.plugin
+	s.httpClientOnce.Do(func() {
+		httpClient, err := hackatime.NewHTTPClient(config.HTTPOptions(s.settings))
+		if err != nil {
+			slog.Warn("HTTPClientFailed", "error", err)
+			return
+		}
+		s.httpClient = httpClient
+	})
+	if s.httpClient != nil {
+		client.HTTPClient = s.httpClient
+	}
+	return client
+}
+
+func (s *Server) apiClient() (*hackatime.Client, error) {
+	apiKey := config.ApiKey()
+	if apiKey == "" {
+		return nil, errors.New("no api_key in ~/.wakatime.cfg")
+	}
+	return s.newClient(apiKey), nil
+}
+
+func (s *Server) backendSender(b config.Backend) hackatime.SendFunc {
+	if s.directAPI() {
+		return hackatime.NewAPISender(func() (*hackatime.Client, error) {
+			return s.newClientFor(b.ApiUrl, b.ApiKey), nil
+		})
+	}
+	return hackatime.NewCLISender(s.runner, s.cliPath, func() hackatime.CLIOptions {
+		options := s.cliOptions()
+		options.ApiUrl = b.ApiUrl
+		options.ApiKey = b.ApiKey
+		return options
+	})
+}
+
+func (s *Server) accountSender(primary hackatime.SendFunc) func(name string) hackatime.SendFunc {
+	return func(name string) hackatime.SendFunc {
+		account, ok := config.FindAccount(name)
+		if !ok {
+			slog.Warn("AccountUnknown", "account", name)
+			return primary
+		}
+		return s.backendSender(account.Destination())
+	}
+}
+
+func (s *Server) directAPI() bool {
+	return !s.mockCLI && s.settings.Bool("direct_api", false)
+}
+
+func (s *Server) cliOptions() hackatime.CLIOptions {
+	return hackatime.CLIOptions{
+		ApiKey:                config.ApiKey(),
+		ApiUrl:                config.ApiUrl(),
+		ExcludeUnknownProject: s.settings.Bool("exclude_unknown_project", false),
+		ConfigFile:            config.FilePath(),
+		LogFile:               config.LogFilePath(),
+		Timeout:               time.Duration(s.settings.Int("cli_timeout", 0)) * time.Second,
+	}
+}
+
+func (s *Server) queueHeartbeat(hb hackatime.Heartbeat) {
+	defer recoverPanic("queue")
+	hb = heartbeat.AttributeReviewBuffer(hb)
+	if hb.AlternateProject == "" && s.projectRoot != "" {
+		hb.AlternateProject = filepath.Base(config.MapPath(s.projectRoot))
+	}
+	if hb.ProjectFolder == "" && s.projectFolder != "" {
+		hb.ProjectFolder = s.projectFolder
+	}
+	if hb.Branch == "" && hb.EntityType == "file" && s.directAPI() {
+		hb.Branch = heartbeat.GitBranch(cmp.Or(hb.LocalFile, hb.Entity))
+	}
+	hb = heartbeat.MapContainerPaths(hb)
+	hb.Account = config.AccountFor(hb.AlternateProject, hb.Entity)
+	if hb.Hostname == "" {
+		hb.Hostname = s.settings.String("hostname")
+	}
+	hb = heartbeat.HideFileName(hb)
+	hb = heartbeat.HideHomeDir(hb, s.settings)
+
+	logEvent("HeartbeatQueued", hb)
+	if !s.forwardToDaemon(hb) {
+		s.queue.Add(hb)
+		s.backends.Add(hb)
+	}
+	s.trackHeartbeat(hb)
+	s.metrics.add(func(counts *MetricCounts) { counts.Queued++ })
+}
+
+func (s *Server) throttledHeartbeat(hb hackatime.Heartbeat) {
+	hb, ok := s.stripBinaryMetadata(hb)
+	if !ok {
+		s.metrics.add(func(counts *MetricCounts) { counts.Dropped++ })
+		return
+	}
+
+	if reason := heartbeat.SkipReason(hb, s.projectRoot, s.settings); reason != "" {
+		slog.Debug("HeartbeatSkipped", "entity", hb.Entity, "project", hb.AlternateProject, "result", reason)
+		s.metrics.add(func(counts *MetricCounts) { counts.Dropped++ })
+		return
+	}
+	s.recordFileTime(hb)
+
+	if s.throttle.Allow(hb) {
+		s.scheduler.Cancel(hb.Entity)
+		s.sendThrottled(hb)
+		return
+	}
+	if s.throttle.Duplicate(hb) {
+		s.metrics.add(func(counts *MetricCounts) { counts.Deduped++ })
+		return
+	}
+	s.metrics.add(func(counts *MetricCounts) { counts.Throttled++ })
+
+	s.scheduler.Debounce(hb.Entity, s.throttle.Interval(), func() {
+		defer recoverPanic("debounce")
+		if s.throttle.Allow(hb) {
+			logEvent("Debounced", hb)
+			s.sendThrottled(hb)
+		}
+	})
+}
+
+func (s *Server) sendThrottled(hb hackatime.Heartbeat) {
+	hb = applyLineChanges(hb, s.takeLineChanges(hb.Entity))
+	s.limiter.Go(func() {
+		if s.ownsHeartbeat(hb) {
+			s.queueHeartbeat(hb)
+		}
+	})
+}
+
+func (s *Server) stripBinaryMetadata(hb hackatime.Heartbeat) (hackatime.Heartbeat, bool) {
+	if !s.isBinaryFile(hb.Entity) {
+		return hb, true
+	}
+	if config.Value("binary_file_action") == heartbeat.FileActionSkip {
+		return hb, false
+	}
+
+	hb.Lines = 0
+	hb.LineNumber = 0
+	hb.CursorPos = 0
+	return hb, true
+}
+
+func (s *Server) loggedSender(send hackatime.SendFunc) hackatime.SendFunc {
+	return func(heartbeats []hackatime.Heartbeat) (err error) {
+		defer recoverPanic("send")
+
+		started := time.Now()
+		err = send(heartbeats)
+		s.recordSendTime(time.Since(started), err)
+		defer s.trackSend(err)
+		s.sendStats.record(len(heartbeats), s.limiter.stats())
+		if hackatime.IsTimeout(err) {
+			s.metrics.add(func(counts *MetricCounts) { counts.Timeouts++ })
+		}
+
+		var partial *hackatime.PartialError
+		if errors.As(err, &partial) && len(partial.Retry) < len(heartbeats) {
+			s.logPartialSend(heartbeats, partial)
+			return err
+		}
+		if wait, limited := hackatime.RetryAfter(err); limited {
+			slog.Warn("RateLimited", "heartbeats", len(heartbeats), "retry_after", wait.String())
+			return err
+		}
+		if _, retry := hackatime.RetryDelay(err); retry {
+			slog.Debug("HeartbeatsDeferred", "heartbeats", len(heartbeats), "result", err)
+			return err
+		}
+
+		result := "ok"
+		if err != nil {
+			result = err.Error()
+			s.metrics.add(func(counts *MetricCounts) { counts.Failed += len(heartbeats) })
+			slog.Error("HeartbeatsFailed", "heartbeats", len(heartbeats), "result", err)
+		} else {
+			s.metrics.add(func(counts *MetricCounts) { counts.Sent += len(heartbeats) })
+			s.recordHistory(heartbeats)
+			slog.Info("HeartbeatsSent", "heartbeats", len(heartbeats), "result", result)
+		}
+		for _, hb := range heartbeats {
+			if err != nil {
+				slog.Info("HeartbeatFailed", "entity", hb.Entity, "project", hb.AlternateProject, "result", result, "heartbeat", hb)
+				continue
+			}
+			slog.Debug("HeartbeatSent", "entity", hb.Entity, "project", hb.AlternateProject, "time", hb.Time, "result", result)
+		}
+		return err
+	}
+}
+
+func (s *Server) logPartialSend(heartbeats []hackatime.Heartbeat, partial *hackatime.PartialError) {
+	accepted := partial.Accepted(heartbeats)
+	s.metrics.add(func(counts *MetricCounts) {
+		counts.Sent += len(accepted)
+		counts.Failed += len(partial.Rejected)
+	})
+	s.recordHistory(accepted)
+	slog.Warn("HeartbeatsPartiallySent", "heartbeats", len(heartbeats), "sent", len(accepted), "rejected", len(partial.Rejected), "retrying", len(partial.Retry), "result", partial)
+	for _, hb := range accepted {
+		slog.Debug("HeartbeatSent", "entity", hb.Entity, "project", hb.AlternateProject, "time", hb.Time, "result", "ok")
+	}
+	logging.Rejected(partial.Rejected)
+}
+
+func (s *Server) logConnectivity(online bool, synced int) {
+	if online {
+		slog.Info("BackOnline", "synced", synced)
+		return
+	}
+	slog.Warn("Offline", "queued", s.queue.Len())
+}
+
+func logEvent(eventType string, hb hackatime.Heartbeat) {
+	slog.Debug(eventType, "entity", hb.Entity, "project", hb.AlternateProject, "heartbeat", hb)
+}
+
+func logMockCall(call hackatime.Call) {
+	logged := hackatime.RedactArgs(call.Args)
+	if call.Stdin != nil {
+		slog.Info("MockCLI", "cli", call.Name, "args", logged, "extra_heartbeats", json.RawMessage(call.Stdin))
+		return
+	}
+	slog.Info("MockCLI", "cli", call.Name, "args", logged)
+}
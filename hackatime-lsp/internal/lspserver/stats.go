@@ -0,0 +1,46 @@
+package lspserver
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const sendStatsInterval = time.Hour
+
+type sendStats struct {
+	mutex      sync.Mutex
+	since      time.Time
+	heartbeats int
+	processes  int
+}
+
+func (st *sendStats) record(heartbeats int, goroutines limiterStats) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	now := time.Now()
+	if st.since.IsZero() {
+		st.since = now
+	}
+	st.heartbeats += heartbeats
+	st.processes++
+
+	if now.Sub(st.since) < sendStatsInterval {
+		return
+	}
+
+	hours := now.Sub(st.since).Hours()
+	slog.Info("SendStats",
+		"heartbeats_per_hour", float64(st.heartbeats)/hours,
+		"processes_per_hour", float64(st.processes)/hours,
+		"processes_saved", st.heartbeats-st.processes,
+		"goroutine_limit", goroutines.Limit,
+		"goroutines_in_flight", goroutines.InFlight,
+		"goroutines_peak", goroutines.Peak,
+		"goroutine_waits", goroutines.Waited,
+	)
+	st.since = now
+	st.heartbeats = 0
+	st.processes = 0
+}
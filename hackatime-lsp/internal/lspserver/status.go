@@ -0,0 +1,38 @@
+package lspserver
+
+import (
+	"github.com/tliron/glsp"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+const methodStatus = "hackatime/status"
+
+type StatusResult struct {
+	Queued       int                        `json:"queued"`
+	Paused       bool                       `json:"paused"`
+	Today        string                     `json:"today,omitempty"`
+	LocalToday   string                     `json:"local_today,omitempty"`
+	ProjectToday string                     `json:"project_today,omitempty"`
+	Metrics      *MetricCounts              `json:"metrics,omitempty"`
+	Leaderboard  *hackatime.LeaderboardRank `json:"leaderboard,omitempty"`
+}
+
+func (s *Server) handleStatus(ctx *glsp.Context) (any, error) {
+	return s.statusResult(), nil
+}
+
+func (s *Server) statusResult() StatusResult {
+	result := StatusResult{
+		Queued:       s.queue.Len(),
+		Paused:       s.queue.Paused(),
+		Today:        s.todayText(),
+		LocalToday:   s.localToday(),
+		ProjectToday: s.daemonWorkspaceToday(),
+		Leaderboard:  s.leaderboardRank(),
+	}
+	if counts, enabled := s.metrics.snapshot(); enabled {
+		result.Metrics = &counts
+	}
+	return result
+}
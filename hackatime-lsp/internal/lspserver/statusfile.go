@@ -0,0 +1,130 @@
+package lspserver
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+type lastHeartbeat struct {
+	Entity  string    `json:"entity"`
+	Project string    `json:"project"`
+	Time    time.Time `json:"time"`
+}
+
+type statusFile struct {
+	StatusResult
+	Running       bool           `json:"running"`
+	Pid           int            `json:"pid"`
+	StartedAt     time.Time      `json:"started_at"`
+	Project       string         `json:"project"`
+	LastHeartbeat *lastHeartbeat `json:"last_heartbeat,omitempty"`
+	LastSent      *time.Time     `json:"last_sent,omitempty"`
+	LastError     string         `json:"last_error,omitempty"`
+	LastErrorAt   *time.Time     `json:"last_error_at,omitempty"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+type statusTracker struct {
+	path          string
+	lastHeartbeat *lastHeartbeat
+	lastSent      *time.Time
+	lastError     string
+	lastErrorAt   *time.Time
+}
+
+func (s *Server) loadStatusFile() {
+	path := s.settings.String("status_file")
+	if path == "" {
+		path = config.StatusFilePath()
+	}
+	if !s.settings.Bool("write_status_file", true) {
+		path = ""
+	}
+
+	s.statusMutex.Lock()
+	s.status.path = path
+	s.statusMutex.Unlock()
+}
+
+func (s *Server) trackHeartbeat(hb hackatime.Heartbeat) {
+	s.statusMutex.Lock()
+	defer s.statusMutex.Unlock()
+
+	s.status.lastHeartbeat = &lastHeartbeat{
+		Entity:  hb.Entity,
+		Project: hb.AlternateProject,
+		Time:    time.UnixMilli(int64(hb.Time * 1000)),
+	}
+}
+
+func (s *Server) trackSend(err error) {
+	now := time.Now()
+
+	s.statusMutex.Lock()
+	if err != nil {
+		s.status.lastError = err.Error()
+		s.status.lastErrorAt = &now
+	} else {
+		s.status.lastSent = &now
+	}
+	s.statusMutex.Unlock()
+
+	s.writeStatusFile(true)
+}
+
+func (s *Server) writeStatusFile(running bool) {
+	s.statusMutex.Lock()
+	path := s.status.path
+	status := statusFile{
+		Running:       running,
+		Pid:           os.Getpid(),
+		StartedAt:     s.startedAt,
+		Project:       s.projectRoot,
+		LastHeartbeat: s.status.lastHeartbeat,
+		LastSent:      s.status.lastSent,
+		LastError:     s.status.lastError,
+		LastErrorAt:   s.status.lastErrorAt,
+		UpdatedAt:     time.Now(),
+	}
+	s.statusMutex.Unlock()
+
+	if path == "" {
+		return
+	}
+	status.StatusResult = s.statusResult()
+
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := writeFileAtomic(config.ExpandHome(path), data); err != nil {
+		slog.Debug("StatusFileFailed", "path", path, "error", err)
+	}
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,69 @@
+package lspserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/tliron/glsp"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+const (
+	methodSummaries  = "hackatime/summaries"
+	summariesTimeout = 10 * time.Second
+)
+
+type SummariesParams struct {
+	Start   string `json:"start"`
+	End     string `json:"end"`
+	Project string `json:"project"`
+}
+
+type SummariesResult struct {
+	*hackatime.Summaries
+	Projects  []hackatime.SummaryItem `json:"projects"`
+	Languages []hackatime.SummaryItem `json:"languages"`
+}
+
+func (s *Server) handleSummaries(ctx *glsp.Context) (any, error) {
+	var params SummariesParams
+	if len(ctx.Params) > 0 {
+		if err := json.Unmarshal(ctx.Params, &params); err != nil {
+			return nil, err
+		}
+	}
+
+	query := hackatime.SummariesQuery{Project: params.Project}
+	var err error
+	if params.Start != "" {
+		if query.Start, err = hackatime.ParseDate(params.Start); err != nil {
+			return nil, err
+		}
+	}
+	if params.End != "" {
+		if query.End, err = hackatime.ParseDate(params.End); err != nil {
+			return nil, err
+		}
+	}
+
+	apiKey := config.ApiKey()
+	if apiKey == "" {
+		return nil, errors.New("no api_key configured")
+	}
+
+	requestCtx, cancel := context.WithTimeout(context.Background(), summariesTimeout)
+	defer cancel()
+
+	summaries, err := s.newClient(apiKey).Summaries(requestCtx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := SummariesResult{Summaries: summaries}
+	result.Projects, result.Languages = summaries.Totals()
+	return result, nil
+}
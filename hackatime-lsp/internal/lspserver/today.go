@@ -0,0 +1,57 @@
+package lspserver
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/tliron/glsp"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+const (
+	methodToday                 = "hackatime/today"
+	defaultTodayIntervalMinutes = 10
+)
+
+type TodayParams struct {
+	Text string `json:"text"`
+}
+
+func (s *Server) startToday(ctx *glsp.Context) {
+	if !s.settings.Bool("show_today", false) {
+		return
+	}
+
+	interval := time.Duration(s.settings.Int("today_interval", defaultTodayIntervalMinutes)) * time.Minute
+	notify := ctx.Notify
+	s.whileActive(methodToday, interval, func() {
+		s.refreshToday(notify)
+	})
+}
+
+func (s *Server) refreshToday(notify glsp.NotifyFunc) {
+	text, err := hackatime.Today(s.runner, s.cliPath, s.cliOptions())
+	if err != nil {
+		slog.Debug("TodayFailed", "result", err)
+		return
+	}
+
+	s.statusMutex.Lock()
+	changed := text != s.today
+	s.today = text
+	s.statusMutex.Unlock()
+
+	if changed && text != "" {
+		slog.Debug("Today", "today", text)
+		notify(methodToday, TodayParams{Text: text})
+		s.writeStatusFile(true)
+	}
+}
+
+func (s *Server) todayText() string {
+	s.statusMutex.Lock()
+	defer s.statusMutex.Unlock()
+
+	return s.today
+}
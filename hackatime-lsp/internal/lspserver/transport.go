@@ -0,0 +1,134 @@
+package lspserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/tliron/glsp"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/pipe"
+)
+
+func ParseListenAddress(address string) (network string, addr string, err error) {
+	network, addr, found := strings.Cut(address, ":")
+	if !found || addr == "" {
+		return "", "", fmt.Errorf("invalid listen address %q, expected tcp:HOST:PORT, unix:PATH or pipe:NAME", address)
+	}
+	switch network {
+	case "tcp":
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return "", "", fmt.Errorf("invalid listen address %q: %w", address, err)
+		}
+	case "unix":
+	case "pipe":
+		addr = pipe.Path(addr)
+	default:
+		return "", "", fmt.Errorf("invalid listen address %q, expected tcp:HOST:PORT, unix:PATH or pipe:NAME", address)
+	}
+	return network, addr, nil
+}
+
+func (s *Server) RunListener(address string) error {
+	network, addr, err := ParseListenAddress(address)
+	if err != nil {
+		return err
+	}
+
+	if network == "unix" {
+		os.Remove(addr)
+		if err := os.MkdirAll(filepath.Dir(addr), 0755); err != nil {
+			return err
+		}
+	}
+	var listener net.Listener
+	if network == "pipe" {
+		listener, err = pipe.Listen(addr)
+	} else {
+		listener, err = net.Listen(network, addr)
+	}
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	if network == "unix" {
+		os.Chmod(addr, 0600)
+		defer os.Remove(addr)
+	}
+
+	s.startDebugServer()
+	s.closeOnSignal(func() {
+		if network == "unix" {
+			os.Remove(addr)
+		}
+	})
+
+	slog.Info("Listening", "address", listener.Addr().String(), "network", network)
+	for !s.closed.Load() {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				break
+			}
+			s.Close()
+			return err
+		}
+
+		slog.Info("ClientConnected", "remote", conn.RemoteAddr().String())
+		s.serveConn(conn)
+		slog.Info("ClientDisconnected", "remote", conn.RemoteAddr().String())
+	}
+	s.Close()
+	return nil
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	handler := s.handler()
+	stream := jsonrpc2.NewBufferedStream(conn, jsonrpc2.VSCodeObjectCodec{})
+	rpc := jsonrpc2.NewConn(context.Background(), stream, jsonrpc2.HandlerWithError(func(ctx context.Context, rpc *jsonrpc2.Conn, request *jsonrpc2.Request) (any, error) {
+		glspContext := glsp.Context{
+			Method: request.Method,
+			Notify: func(method string, params any) {
+				if err := rpc.Notify(ctx, method, params); err != nil {
+					slog.Debug("NotifyFailed", "method", method, "error", err)
+				}
+			},
+			Call: func(method string, params any, result any) {
+				if err := rpc.Call(ctx, method, params, result); err != nil {
+					slog.Debug("CallFailed", "method", method, "error", err)
+				}
+			},
+		}
+		if request.Params != nil {
+			glspContext.Params = *request.Params
+		}
+
+		r, validMethod, validParams, err := handler.Handle(&glspContext)
+		switch {
+		case request.Method == "exit":
+			s.Close()
+			return nil, rpc.Close()
+		case !validMethod:
+			return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound, Message: "method not supported: " + request.Method}
+		case !validParams:
+			return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: errorMessage(err)}
+		case err != nil:
+			return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidRequest, Message: err.Error()}
+		}
+		return r, nil
+	}))
+	<-rpc.DisconnectNotify()
+}
+
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
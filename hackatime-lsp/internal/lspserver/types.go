@@ -0,0 +1,13 @@
+package lspserver
+
+import protocol "github.com/tliron/glsp/protocol_3_16"
+
+type InitializeResult struct {
+	Capabilities ServerCapabilities                   `json:"capabilities"`
+	ServerInfo   *protocol.InitializeResultServerInfo `json:"serverInfo,omitempty"`
+}
+
+type ServerCapabilities struct {
+	protocol.ServerCapabilities
+	PositionEncoding string `json:"positionEncoding,omitempty"`
+}
@@ -0,0 +1,48 @@
+package lspserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/update"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/version"
+)
+
+const updateCheckTimeout = 30 * time.Second
+
+func (s *Server) startUpdateCheck(ctx *glsp.Context) {
+	if !s.settings.Bool("check_for_updates", false) {
+		return
+	}
+
+	notify := ctx.Notify
+	go func() {
+		defer recoverPanic("update")
+		s.checkForUpdate(notify)
+	}()
+}
+
+func (s *Server) checkForUpdate(notify glsp.NotifyFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), updateCheckTimeout)
+	defer cancel()
+
+	release, err := update.Latest(ctx)
+	if err != nil {
+		slog.Debug("UpdateCheckFailed", "result", err)
+		return
+	}
+	if !update.Newer(release.Version(), version.Version) {
+		return
+	}
+
+	slog.Info("UpdateAvailable", "current", version.Version, "latest", release.Version())
+	notify(protocol.ServerWindowShowMessage, protocol.ShowMessageParams{
+		Type:    protocol.MessageTypeInfo,
+		Message: fmt.Sprintf("Hackatime: %s %s is available (you have %s). Run `%s self-update` to install it.", version.Name, release.Version(), version.Version, version.Name),
+	})
+}
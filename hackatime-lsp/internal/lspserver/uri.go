@@ -0,0 +1,253 @@
+package lspserver
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+)
+
+type documentRef struct {
+	Entity        string
+	LocalFile     string
+	Project       string
+	ProjectFolder string
+	Cell          string
+	IsUnsaved     bool
+}
+
+func (d documentRef) key() string {
+	if d.Cell == "" {
+		return d.Entity
+	}
+	return d.Entity + "#" + d.Cell
+}
+
+func (s *Server) documentRoot(doc documentRef) string {
+	if doc.ProjectFolder != "" {
+		return doc.ProjectFolder
+	}
+	return s.projectRoot
+}
+
+var remoteSchemes = map[string]bool{
+	"ssh":           true,
+	"sftp":          true,
+	"scp":           true,
+	"vscode-remote": true,
+}
+
+func (s *Server) resolveDocumentURI(uri string) (documentRef, bool) {
+	scheme, rest, found := strings.Cut(uri, ":")
+	if !found || len(scheme) == 1 {
+		return documentRef{}, false
+	}
+
+	switch strings.ToLower(scheme) {
+	case "file":
+		if notebook, _, found := strings.Cut(uri, "#"); found && strings.EqualFold(path.Ext(notebook), ".ipynb") {
+			return s.resolveNotebookCell(uri)
+		}
+		doc := documentRef{Entity: s.canonicalPath(cleanFileURI(uri))}
+		if remote, _, ok := config.RemoteFor("", filepath.ToSlash(doc.Entity)); ok {
+			doc.Project = remote.ProjectName()
+			doc.ProjectFolder = filepath.FromSlash(remote.Path)
+		}
+		return doc, true
+	case "untitled":
+		name := strings.TrimPrefix(rest, "//")
+		if name == "" {
+			name = "untitled"
+		}
+		return documentRef{Entity: name, IsUnsaved: true}, true
+	case "git":
+		if path := gitURIPath(uri); path != "" {
+			return documentRef{Entity: s.canonicalPath(path)}, true
+		}
+		return documentRef{}, false
+	case "vscode-notebook-cell", "notebook-cell":
+		return s.resolveNotebookCell(uri)
+	case "zed":
+		return s.resolveCollabURI(uri)
+	default:
+		if remoteSchemes[strings.ToLower(scheme)] {
+			return s.resolveRemoteURI(uri), true
+		}
+		return documentRef{}, false
+	}
+}
+
+func (s *Server) resolveNotebookCell(uri string) (documentRef, bool) {
+	notebook, cell, _ := strings.Cut(uri, "#")
+	_, notebookPath, _ := strings.Cut(notebook, ":")
+	doc, ok := s.resolveDocumentURI("file:" + notebookPath)
+	doc.Cell = cell
+	return doc, ok
+}
+
+func (s *Server) resolveCollabURI(uri string) (documentRef, bool) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return documentRef{}, false
+	}
+
+	kind := strings.ToLower(u.Host)
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	name := ""
+	switch {
+	case kind == "channel" && parts[0] != "":
+		name = parts[0]
+	case kind == "project" && len(parts) > 1:
+		name = parts[1]
+	default:
+		return documentRef{}, false
+	}
+
+	project := name
+	if configured := s.settings.String("collab_project"); configured != "" {
+		project = strings.ReplaceAll(configured, "{name}", name)
+	}
+	return documentRef{Entity: "zed://" + kind + "/" + strings.Join(parts, "/"), Project: project, IsUnsaved: true}, true
+}
+
+func (s *Server) resolveRemoteURI(uri string) documentRef {
+	doc := documentRef{Entity: uri}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return doc
+	}
+
+	remote, rel, ok := config.RemoteFor(u.Hostname(), u.Path)
+	if !ok {
+		doc.LocalFile = localFileForRemote(s.projectRoot, uri)
+		return doc
+	}
+	doc.Project = remote.ProjectName()
+	if remote.Local == "" {
+		doc.LocalFile = localFileForRemote("", uri)
+		return doc
+	}
+	doc.ProjectFolder = remote.Local
+	localFile := filepath.Join(remote.Local, filepath.FromSlash(rel))
+	if info, err := os.Stat(localFile); err == nil && !info.IsDir() {
+		doc.LocalFile = localFile
+	}
+	return doc
+}
+
+func (s *Server) resolveSymlinks(path string) string {
+	if !s.settings.Bool("resolve_symlinks", false) {
+		return path
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+		return filepath.Join(dir, filepath.Base(path))
+	}
+	return path
+}
+
+func localFileForRemote(projectRoot, uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Path == "" {
+		return ""
+	}
+
+	remotePath := filepath.FromSlash(u.Path)
+	if info, err := os.Stat(remotePath); err == nil && !info.IsDir() {
+		return remotePath
+	}
+	if projectRoot == "" {
+		return ""
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := range parts {
+		candidate := filepath.Join(append([]string{projectRoot}, parts[i:]...)...)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func gitURIPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return ""
+	}
+
+	var query struct {
+		Path string `json:"path"`
+	}
+	if u.RawQuery != "" {
+		if raw, err := url.QueryUnescape(u.RawQuery); err == nil && json.Unmarshal([]byte(raw), &query) == nil && query.Path != "" {
+			return filepath.Clean(query.Path)
+		}
+	}
+
+	if u.Path == "" {
+		return ""
+	}
+	return cleanFileURI("file://" + u.EscapedPath())
+}
+
+func fileURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+func cleanFileURI(uri string) string {
+	rest := strings.TrimPrefix(uri, "file:")
+	host := ""
+	if strings.HasPrefix(rest, "//") {
+		rest = rest[2:]
+		if strings.HasPrefix(rest, "//") {
+			rest = strings.TrimLeft(rest, "/")
+		}
+		if slash := strings.IndexAny(rest, "/\\"); slash >= 0 {
+			host, rest = rest[:slash], rest[slash:]
+		} else {
+			host, rest = rest, ""
+		}
+	}
+	if host == "localhost" {
+		host = ""
+	}
+
+	path := rest
+	if decoded, err := url.PathUnescape(path); err == nil {
+		path = decoded
+	}
+
+	if runtime.GOOS != "windows" {
+		if host != "" {
+			return "//" + host + filepath.Clean("/"+path)
+		}
+		return filepath.Clean(path)
+	}
+
+	path = strings.ReplaceAll(path, "/", "\\")
+	if host != "" {
+		return `\\` + host + filepath.Clean(`\`+strings.TrimLeft(path, `\`))
+	}
+	if len(path) >= 3 && path[0] == '\\' && path[2] == ':' {
+		path = path[1:]
+	}
+	if len(path) >= 2 && path[1] == ':' {
+		path = strings.ToUpper(path[:1]) + path[1:]
+	}
+	if len(path) == 2 && path[1] == ':' {
+		path += `\`
+	}
+	return filepath.Clean(path)
+}
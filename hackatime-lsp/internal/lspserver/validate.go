@@ -0,0 +1,110 @@
+package lspserver
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/config"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/logging"
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/pkg/hackatime"
+)
+
+type configProblem struct {
+	Field   string
+	Message string
+}
+
+func (s *Server) validateConfig() []configProblem {
+	var problems []configProblem
+
+	if err := config.ValidateApiKey(config.ApiKey()); err != nil {
+		problems = append(problems, configProblem{Field: "api_key", Message: err.Error()})
+	}
+	if err := config.ValidateApiUrl(config.ApiUrl()); err != nil {
+		problems = append(problems, configProblem{Field: "api_url", Message: err.Error()})
+	}
+	for _, b := range config.Backends() {
+		if err := config.ValidateApiUrl(b.ApiUrl); err != nil {
+			problems = append(problems, configProblem{Field: "backend." + b.Name + " api_url", Message: err.Error()})
+		}
+		if b.ApiKey == "" {
+			problems = append(problems, configProblem{Field: "backend." + b.Name + " api_key", Message: "no API key set for this backend"})
+		}
+	}
+	for _, account := range config.Accounts() {
+		if account.ApiUrl == "" {
+			continue
+		}
+		if err := config.ValidateApiUrl(account.ApiUrl); err != nil {
+			problems = append(problems, configProblem{Field: "account." + account.Name + " api_url", Message: err.Error()})
+		}
+	}
+	for _, remote := range config.Remotes() {
+		if remote.Local == "" {
+			continue
+		}
+		if info, err := os.Stat(remote.Local); err != nil || !info.IsDir() {
+			problems = append(problems, configProblem{Field: "remote." + remote.Name + " local", Message: remote.Local + " is not a directory"})
+		}
+	}
+	if proxy := s.settings.String("proxy"); proxy != "" {
+		if _, err := hackatime.ParseProxy(proxy); err != nil {
+			problems = append(problems, configProblem{Field: "proxy", Message: err.Error()})
+		}
+	}
+	if certsFile := config.HTTPOptions(s.settings).SSLCertsFile; certsFile != "" {
+		if _, err := hackatime.LoadCertPool(certsFile); err != nil {
+			problems = append(problems, configProblem{Field: "ssl_certs_file", Message: err.Error()})
+		}
+	}
+	if s.mockCLI || s.directAPI() {
+		return problems
+	}
+	if err := hackatime.ValidateCLIPath(s.cliPath); err != nil {
+		problems = append(problems, configProblem{Field: "wakatime-cli", Message: err.Error()})
+	}
+
+	return problems
+}
+
+func (s *Server) forwardLogs(ctx *glsp.Context) {
+	if !s.settings.Bool("forward_logs", true) {
+		logging.Forward(nil)
+		return
+	}
+
+	notify := ctx.Notify
+	logging.Forward(func(level slog.Level, message string) {
+		notify(protocol.ServerWindowLogMessage, protocol.LogMessageParams{
+			Type:    logMessageType(level),
+			Message: "Hackatime: " + message,
+		})
+	})
+}
+
+func logMessageType(level slog.Level) protocol.MessageType {
+	switch {
+	case level >= slog.LevelError:
+		return protocol.MessageTypeError
+	case level >= slog.LevelWarn:
+		return protocol.MessageTypeWarning
+	case level >= slog.LevelInfo:
+		return protocol.MessageTypeInfo
+	default:
+		return protocol.MessageTypeLog
+	}
+}
+
+func (s *Server) reportConfigProblems(ctx *glsp.Context) {
+	for _, problem := range s.validateConfig() {
+		slog.Warn("ConfigInvalid", "field", problem.Field, "error", problem.Message)
+
+		ctx.Notify(protocol.ServerWindowShowMessage, protocol.ShowMessageParams{
+			Type:    protocol.MessageTypeError,
+			Message: "Hackatime: " + problem.Message,
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package pipe
+
+import (
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const Prefix = `\\.\pipe\`
+
+func IsPath(path string) bool {
+	return strings.HasPrefix(path, Prefix)
+}
+
+func Path(name string) string {
+	if IsPath(name) {
+		return name
+	}
+	return Prefix + name
+}
+
+type addr string
+
+func (a addr) Network() string { return "pipe" }
+func (a addr) String() string  { return string(a) }
+
+type conn struct {
+	*os.File
+	path string
+}
+
+func (c *conn) LocalAddr() net.Addr  { return addr(c.path) }
+func (c *conn) RemoteAddr() net.Addr { return addr(c.path) }
+
+func (c *conn) SetDeadline(t time.Time) error      { return c.File.SetDeadline(t) }
+func (c *conn) SetReadDeadline(t time.Time) error  { return c.File.SetReadDeadline(t) }
+func (c *conn) SetWriteDeadline(t time.Time) error { return c.File.SetWriteDeadline(t) }
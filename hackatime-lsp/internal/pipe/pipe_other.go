@@ -0,0 +1,19 @@
+//go:build !windows
+
+package pipe
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+var errUnsupported = errors.New("named pipes are only available on Windows")
+
+func Listen(path string) (net.Listener, error) {
+	return nil, errUnsupported
+}
+
+func Dial(path string, timeout time.Duration) (net.Conn, error) {
+	return nil, errUnsupported
+}
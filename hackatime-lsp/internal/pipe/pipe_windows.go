@@ -0,0 +1,154 @@
+package pipe
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	bufferSize    = 64 * 1024
+	dialRetryWait = 50 * time.Millisecond
+)
+
+type listener struct {
+	path   string
+	closed windows.Handle
+
+	mutex   sync.Mutex
+	pending windows.Handle
+	done    bool
+}
+
+func Listen(path string) (net.Listener, error) {
+	closed, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	l := &listener{path: path, closed: closed}
+
+	l.pending, err = l.create(true)
+	if err != nil {
+		windows.CloseHandle(closed)
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *listener) create(first bool) (windows.Handle, error) {
+	name, err := windows.UTF16PtrFromString(l.path)
+	if err != nil {
+		return windows.InvalidHandle, err
+	}
+
+	flags := uint32(windows.PIPE_ACCESS_DUPLEX | windows.FILE_FLAG_OVERLAPPED)
+	if first {
+		flags |= windows.FILE_FLAG_FIRST_PIPE_INSTANCE
+	}
+	mode := uint32(windows.PIPE_TYPE_BYTE | windows.PIPE_READMODE_BYTE | windows.PIPE_WAIT | windows.PIPE_REJECT_REMOTE_CLIENTS)
+	return windows.CreateNamedPipe(name, flags, mode, windows.PIPE_UNLIMITED_INSTANCES, bufferSize, bufferSize, 0, nil)
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	l.mutex.Lock()
+	if l.done {
+		l.mutex.Unlock()
+		return nil, net.ErrClosed
+	}
+	handle := l.pending
+	l.pending = windows.InvalidHandle
+	l.mutex.Unlock()
+
+	if handle == windows.InvalidHandle {
+		var err error
+		if handle, err = l.create(false); err != nil {
+			return nil, err
+		}
+	}
+	if err := l.waitForClient(handle); err != nil {
+		windows.CloseHandle(handle)
+		return nil, err
+	}
+
+	l.mutex.Lock()
+	if !l.done {
+		if next, err := l.create(false); err == nil {
+			l.pending = next
+		}
+	}
+	l.mutex.Unlock()
+	return &conn{File: os.NewFile(uintptr(handle), l.path), path: l.path}, nil
+}
+
+func (l *listener) waitForClient(handle windows.Handle) error {
+	event, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(event)
+
+	overlapped := windows.Overlapped{HEvent: event}
+	err = windows.ConnectNamedPipe(handle, &overlapped)
+	if err == nil || errors.Is(err, windows.ERROR_PIPE_CONNECTED) {
+		return nil
+	}
+	if !errors.Is(err, windows.ERROR_IO_PENDING) {
+		return err
+	}
+
+	signaled, err := windows.WaitForMultipleObjects([]windows.Handle{event, l.closed}, false, windows.INFINITE)
+	if err != nil {
+		return err
+	}
+	if signaled != windows.WAIT_OBJECT_0 {
+		windows.CancelIoEx(handle, &overlapped)
+		var transferred uint32
+		windows.GetOverlappedResult(handle, &overlapped, &transferred, true)
+		return net.ErrClosed
+	}
+
+	var transferred uint32
+	return windows.GetOverlappedResult(handle, &overlapped, &transferred, false)
+}
+
+func (l *listener) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.done {
+		return nil
+	}
+	l.done = true
+	if l.pending != windows.InvalidHandle {
+		windows.CloseHandle(l.pending)
+		l.pending = windows.InvalidHandle
+	}
+	return windows.SetEvent(l.closed)
+}
+
+func (l *listener) Addr() net.Addr {
+	return addr(l.path)
+}
+
+func Dial(path string, timeout time.Duration) (net.Conn, error) {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		handle, err := windows.CreateFile(name, windows.GENERIC_READ|windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING, windows.FILE_FLAG_OVERLAPPED, 0)
+		if err == nil {
+			return &conn{File: os.NewFile(uintptr(handle), path), path: path}, nil
+		}
+		if !errors.Is(err, windows.ERROR_PIPE_BUSY) || time.Now().After(deadline) {
+			return nil, err
+		}
+		time.Sleep(dialRetryWait)
+	}
+}
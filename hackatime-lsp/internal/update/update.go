@@ -0,0 +1,237 @@
+package update
+
+import (
+	"archive/zip"
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/espcaa/hackatime-zed/hackatime-lsp/internal/version"
+)
+
+const (
+	latestReleaseURL   = "https://api.github.com/repos/espcaa/hackatime-zed/releases/latest"
+	checksumsAssetName = "checksums.txt"
+	maxAssetSize       = 100 * 1024 * 1024
+)
+
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+type Release struct {
+	TagName string  `json:"tag_name"`
+	Assets  []Asset `json:"assets"`
+}
+
+func (r *Release) Version() string {
+	return strings.TrimPrefix(r.TagName, "v")
+}
+
+func Latest(ctx context.Context) (*Release, error) {
+	resp, err := fetch(ctx, latestReleaseURL, "application/vnd.github+json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, err
+	}
+	if release.TagName == "" {
+		return nil, errors.New("latest release has no tag")
+	}
+	return &release, nil
+}
+
+func Newer(latest, current string) bool {
+	if current == "" || current == "dev" {
+		return false
+	}
+	return compareVersions(strings.TrimPrefix(latest, "v"), strings.TrimPrefix(current, "v")) > 0
+}
+
+func AssetName() (string, error) {
+	arch := map[string]string{"amd64": "x86_64", "arm64": "aarch64"}[runtime.GOARCH]
+	platform := map[string]string{"darwin": "apple-darwin", "linux": "unknown-linux-gnu", "windows": "pc-windows-msvc"}[runtime.GOOS]
+	if arch == "" || platform == "" {
+		return "", fmt.Errorf("no release builds for %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+	return fmt.Sprintf("%s-%s-%s.zip", version.Name, arch, platform), nil
+}
+
+func Apply(ctx context.Context, release *Release, executable string) error {
+	name, err := AssetName()
+	if err != nil {
+		return err
+	}
+
+	asset := release.asset(name)
+	if asset == nil {
+		return fmt.Errorf("release %s has no %s", release.TagName, name)
+	}
+	checksums := release.asset(checksumsAssetName)
+	if checksums == nil {
+		return fmt.Errorf("release %s has no %s", release.TagName, checksumsAssetName)
+	}
+
+	sums, err := download(ctx, checksums.URL)
+	if err != nil {
+		return err
+	}
+	data, err := download(ctx, asset.URL)
+	if err != nil {
+		return err
+	}
+	if err := verifyChecksum(sums, name, data); err != nil {
+		return err
+	}
+
+	binary, err := extractBinary(data)
+	if err != nil {
+		return err
+	}
+	return replace(executable, binary)
+}
+
+func (r *Release) asset(name string) *Asset {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+func verifyChecksum(sums []byte, name string, data []byte) error {
+	sum := sha256.Sum256(data)
+	for _, line := range strings.Split(string(sums), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != name {
+			continue
+		}
+		if !strings.EqualFold(fields[0], hex.EncodeToString(sum[:])) {
+			return fmt.Errorf("%s doesn't match its checksum", name)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s has no checksum for %s", checksumsAssetName, name)
+}
+
+func download(ctx context.Context, url string) ([]byte, error) {
+	resp, err := fetch(ctx, url, "application/octet-stream")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxAssetSize))
+}
+
+func extractBinary(data []byte) ([]byte, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	binaryName := version.Name
+	if runtime.GOOS == "windows" {
+		binaryName += ".exe"
+	}
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || filepath.Base(file.Name) != binaryName {
+			continue
+		}
+		reader, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(io.LimitReader(reader, maxAssetSize))
+	}
+	return nil, fmt.Errorf("%s not found in the release archive", binaryName)
+}
+
+func replace(executable string, binary []byte) error {
+	newPath := executable + ".new"
+	if err := os.WriteFile(newPath, binary, 0755); err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "windows" {
+		oldPath := executable + ".old"
+		os.Remove(oldPath)
+		if err := os.Rename(executable, oldPath); err != nil {
+			os.Remove(newPath)
+			return err
+		}
+	}
+	if err := os.Rename(newPath, executable); err != nil {
+		os.Remove(newPath)
+		return err
+	}
+	return nil
+}
+
+func fetch(ctx context.Context, url, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("User-Agent", version.UserAgent())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return resp, nil
+}
+
+func compareVersions(a, b string) int {
+	aCore, aPre, _ := strings.Cut(a, "-")
+	bCore, bPre, _ := strings.Cut(b, "-")
+
+	aParts, bParts := strings.Split(aCore, "."), strings.Split(bCore, ".")
+	for i := 0; i < max(len(aParts), len(bParts)); i++ {
+		var x, y int
+		if i < len(aParts) {
+			x, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			y, _ = strconv.Atoi(bParts[i])
+		}
+		if x != y {
+			return cmp.Compare(x, y)
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	default:
+		return strings.Compare(aPre, bPre)
+	}
+}
@@ -0,0 +1,74 @@
+package version
+
+import (
+	"runtime/debug"
+	"strings"
+)
+
+const (
+	Name   = "hackatime-ls"
+	plugin = "hackatime-zed"
+)
+
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+func init() {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	if Version == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		Version = strings.TrimPrefix(info.Main.Version, "v")
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if Commit == "" {
+				Commit = setting.Value
+			}
+		case "vcs.time":
+			if Date == "" {
+				Date = setting.Value
+			}
+		}
+	}
+}
+
+func String() string {
+	var details []string
+	if Commit != "" {
+		details = append(details, shortCommit(Commit))
+	}
+	if Date != "" {
+		details = append(details, Date)
+	}
+	if len(details) == 0 {
+		return Version
+	}
+	return Version + " (" + strings.Join(details, ", ") + ")"
+}
+
+func UserAgent() string {
+	return plugin + "/" + strings.TrimPrefix(Version, "v")
+}
+
+func Plugin(editor, editorVersion string) string {
+	if editor == "" {
+		editor = "Zed"
+	}
+	if editorVersion != "" {
+		editor += "/" + editorVersion
+	}
+	return editor + " " + UserAgent()
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
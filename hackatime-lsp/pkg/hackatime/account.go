@@ -0,0 +1,30 @@
+package hackatime
+
+func PerAccount(primary SendFunc, forAccount func(account string) SendFunc) SendFunc {
+	return func(heartbeats []Heartbeat) error {
+		var accounts []string
+		groups := make(map[string][]Heartbeat)
+		for _, hb := range heartbeats {
+			if _, seen := groups[hb.Account]; !seen {
+				accounts = append(accounts, hb.Account)
+			}
+			groups[hb.Account] = append(groups[hb.Account], hb)
+		}
+
+		failure := &PartialError{}
+		for _, account := range accounts {
+			send := primary
+			if account != "" {
+				send = forAccount(account)
+			}
+			err := send(groups[account])
+			if len(accounts) == 1 {
+				return err
+			}
+			if err != nil {
+				failure.add(partialFailure(groups[account], err))
+			}
+		}
+		return failure.orNil()
+	}
+}
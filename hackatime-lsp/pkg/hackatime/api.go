@@ -0,0 +1,421 @@
+package hackatime
+
+import (
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	apiTimeout         = 15 * time.Second
+	dateLayout         = "2006-01-02"
+	bulkLimit          = 25
+	bulkEndpoint       = "/users/current/heartbeats.bulk"
+	heartbeatsEndpoint = "/users/current/heartbeats"
+
+	defaultRetryAfter = time.Minute
+	maxRetryAfter     = time.Hour
+)
+
+type Client struct {
+	ApiUrl      string
+	ApiKey      string
+	UserAgent   string
+	MachineName string
+	HTTPClient  *http.Client
+
+	NoCompression bool
+}
+
+func NewClient(apiUrl, apiKey string) *Client {
+	if apiUrl == "" {
+		apiUrl = DefaultApiUrl
+	}
+	return &Client{
+		ApiUrl:     strings.TrimSuffix(apiUrl, "/"),
+		ApiKey:     apiKey,
+		HTTPClient: &http.Client{Timeout: apiTimeout},
+	}
+}
+
+type APIError struct {
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	message := e.Message
+	if message == "" {
+		message = http.StatusText(e.StatusCode)
+	}
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("hackatime api: %d %s (retrying in %s)", e.StatusCode, message, e.RetryAfter)
+	}
+	return fmt.Sprintf("hackatime api: %d %s", e.StatusCode, message)
+}
+
+func RetryAfter(err error) (time.Duration, bool) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	return apiErr.RetryAfter, true
+}
+
+func IsOffline(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	switch {
+	case errors.As(err, &dnsErr):
+		return true
+	case errors.As(err, &opErr) && (opErr.Op == "dial" || opErr.Op == "proxyconnect"):
+		return true
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET):
+		return true
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && urlErr.Timeout()
+}
+
+func parseRetryAfter(header string) time.Duration {
+	wait := defaultRetryAfter
+	if seconds, err := strconv.Atoi(strings.TrimSpace(header)); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(header); err == nil {
+		wait = time.Until(at)
+	}
+	return min(max(wait, time.Second), maxRetryAfter)
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values, out any) error {
+	return c.do(ctx, http.MethodGet, path, query, nil, false, out)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body any, compress bool, out any) error {
+	endpoint := c.ApiUrl + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := encodeBody(body, compress)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(c.ApiKey)))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if body != nil && compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	if c.MachineName != "" {
+		req.Header.Set("X-Machine-Name", c.MachineName)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var body struct {
+			Error string `json:"error"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		json.Unmarshal(data, &body)
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: body.Error}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			apiErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		}
+		return apiErr
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func encodeBody(body any, compress bool) ([]byte, error) {
+	data, err := json.Marshal(body)
+	if err != nil || !compress {
+		return data, err
+	}
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type APIHeartbeat struct {
+	Entity    string  `json:"entity"`
+	Type      string  `json:"type"`
+	Category  string  `json:"category,omitempty"`
+	Time      float64 `json:"time"`
+	Project   string  `json:"project,omitempty"`
+	Language  string  `json:"language,omitempty"`
+	Lines     int     `json:"lines,omitempty"`
+	LineNo    int     `json:"lineno,omitempty"`
+	CursorPos int     `json:"cursorpos,omitempty"`
+	IsWrite   bool    `json:"is_write,omitempty"`
+	IsUnsaved bool    `json:"is_unsaved_entity,omitempty"`
+	Branch    string  `json:"branch,omitempty"`
+
+	LineAdditions    int `json:"line_additions,omitempty"`
+	LineDeletions    int `json:"line_deletions,omitempty"`
+	AILineChanges    int `json:"ai_line_changes,omitempty"`
+	HumanLineChanges int `json:"human_line_changes,omitempty"`
+
+	CharsTyped   int `json:"chars_typed,omitempty"`
+	CharsDeleted int `json:"chars_deleted,omitempty"`
+	EditEvents   int `json:"edit_events,omitempty"`
+}
+
+func ToAPIHeartbeat(hb Heartbeat) APIHeartbeat {
+	return APIHeartbeat{
+		Entity:    hb.Entity,
+		Type:      hb.EntityType,
+		Category:  hb.Category,
+		Time:      hb.Time,
+		Project:   hb.AlternateProject,
+		Language:  WakatimeLanguage(hb.Language),
+		Lines:     hb.Lines,
+		LineNo:    hb.LineNumber,
+		CursorPos: hb.CursorPos,
+		IsWrite:   hb.IsWrite,
+		IsUnsaved: hb.IsUnsaved,
+		Branch:    hb.Branch,
+
+		LineAdditions:    hb.LineAdditions,
+		LineDeletions:    hb.LineDeletions,
+		AILineChanges:    hb.AILineChanges,
+		HumanLineChanges: hb.HumanLineChanges,
+
+		CharsTyped:   hb.CharsTyped,
+		CharsDeleted: hb.CharsDeleted,
+		EditEvents:   hb.EditEvents,
+	}
+}
+
+func (c *Client) SendHeartbeat(ctx context.Context, hb APIHeartbeat) (json.RawMessage, error) {
+	var response json.RawMessage
+	if err := c.do(ctx, http.MethodPost, heartbeatsEndpoint, nil, hb, false, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *Client) SendHeartbeats(ctx context.Context, heartbeats []APIHeartbeat) (json.RawMessage, error) {
+	var response json.RawMessage
+	err := c.do(ctx, http.MethodPost, bulkEndpoint, nil, heartbeats, !c.NoCompression, &response)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnsupportedMediaType && !c.NoCompression {
+		c.NoCompression = true
+		err = c.do(ctx, http.MethodPost, bulkEndpoint, nil, heartbeats, false, &response)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func NewAPISender(client func() (*Client, error)) SendFunc {
+	return func(heartbeats []Heartbeat) error {
+		if len(heartbeats) == 0 {
+			return nil
+		}
+
+		c, err := client()
+		if err != nil {
+			return err
+		}
+		if heartbeats[0].Plugin != "" {
+			c.UserAgent = heartbeats[0].Plugin
+		}
+		c.MachineName = heartbeats[0].Hostname
+		if c.MachineName == "" {
+			c.MachineName, _ = os.Hostname()
+		}
+		failure := &PartialError{}
+		for i := 0; i < len(heartbeats); i += bulkLimit {
+			batch := heartbeats[i:min(i+bulkLimit, len(heartbeats))]
+			body := make([]APIHeartbeat, 0, len(batch))
+			for _, hb := range batch {
+				body = append(body, ToAPIHeartbeat(hb))
+			}
+
+			response, err := c.SendHeartbeats(context.Background(), body)
+			if err != nil {
+				if _, retry := RetryDelay(err); retry {
+					failure.add(&PartialError{Retry: heartbeats[i:], Err: err})
+					break
+				}
+				failure.add(partialFailure(batch, err))
+				continue
+			}
+			failure.add(parseBulkResponse(batch, response))
+		}
+		if len(failure.Retry) == 0 && len(failure.Rejected) == len(heartbeats) && failure.Err != nil {
+			return failure.Err
+		}
+		return failure.orNil()
+	}
+}
+
+type SummaryItem struct {
+	Name         string  `json:"name"`
+	TotalSeconds float64 `json:"total_seconds"`
+	Text         string  `json:"text"`
+	Percent      float64 `json:"percent"`
+}
+
+type SummaryRange struct {
+	Date  string `json:"date"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+	Text  string `json:"text"`
+}
+
+type Summary struct {
+	GrandTotal SummaryItem   `json:"grand_total"`
+	Range      SummaryRange  `json:"range"`
+	Projects   []SummaryItem `json:"projects"`
+	Languages  []SummaryItem `json:"languages"`
+	Editors    []SummaryItem `json:"editors"`
+	Categories []SummaryItem `json:"categories"`
+}
+
+type Summaries struct {
+	Data            []Summary   `json:"data"`
+	Start           string      `json:"start"`
+	End             string      `json:"end"`
+	CumulativeTotal SummaryItem `json:"cumulative_total"`
+}
+
+type SummariesQuery struct {
+	Start   time.Time
+	End     time.Time
+	Project string
+}
+
+func (c *Client) Summaries(ctx context.Context, q SummariesQuery) (*Summaries, error) {
+	if q.End.IsZero() {
+		q.End = time.Now()
+	}
+	if q.Start.IsZero() {
+		q.Start = q.End
+	}
+	if q.End.Before(q.Start) {
+		return nil, fmt.Errorf("summaries: end %s is before start %s", q.End.Format(dateLayout), q.Start.Format(dateLayout))
+	}
+
+	query := url.Values{}
+	query.Set("start", q.Start.Format(dateLayout))
+	query.Set("end", q.End.Format(dateLayout))
+	if q.Project != "" {
+		query.Set("project", q.Project)
+	}
+
+	var summaries Summaries
+	if err := c.get(ctx, "/users/current/summaries", query, &summaries); err != nil {
+		return nil, err
+	}
+	return &summaries, nil
+}
+
+func (s *Summaries) Totals() (projects, languages []SummaryItem) {
+	return mergeItems(s.Data, func(day Summary) []SummaryItem { return day.Projects }),
+		mergeItems(s.Data, func(day Summary) []SummaryItem { return day.Languages })
+}
+
+func mergeItems(days []Summary, items func(Summary) []SummaryItem) []SummaryItem {
+	var merged []SummaryItem
+	index := make(map[string]int)
+	var total float64
+	for _, day := range days {
+		for _, item := range items(day) {
+			total += item.TotalSeconds
+			if i, exists := index[item.Name]; exists {
+				merged[i].TotalSeconds += item.TotalSeconds
+				continue
+			}
+			index[item.Name] = len(merged)
+			merged = append(merged, SummaryItem{Name: item.Name, TotalSeconds: item.TotalSeconds})
+		}
+	}
+
+	for i := range merged {
+		merged[i].Text = FormatDuration(time.Duration(merged[i].TotalSeconds * float64(time.Second)))
+		if total > 0 {
+			merged[i].Percent = merged[i].TotalSeconds / total * 100
+		}
+	}
+	slices.SortStableFunc(merged, func(a, b SummaryItem) int {
+		return cmp.Compare(b.TotalSeconds, a.TotalSeconds)
+	})
+	return merged
+}
+
+func ParseDate(value string) (time.Time, error) {
+	date, err := time.ParseInLocation(dateLayout, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", value)
+	}
+	return date, nil
+}
+
+func FormatDuration(d time.Duration) string {
+	hours := int(d.Hours())
+	minutes := int(d.Minutes()) % 60
+	switch {
+	case hours > 0 && minutes > 0:
+		return plural(hours, "hr") + " " + plural(minutes, "min")
+	case hours > 0:
+		return plural(hours, "hr")
+	default:
+		return plural(minutes, "min")
+	}
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -0,0 +1,135 @@
+package hackatime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type Rejection struct {
+	Heartbeat Heartbeat
+	Status    int
+	Reason    string
+}
+
+type PartialError struct {
+	Retry    []Heartbeat
+	Rejected []Rejection
+	Err      error
+}
+
+func (e *PartialError) Error() string {
+	message := fmt.Sprintf("%d heartbeats rejected, %d to retry", len(e.Rejected), len(e.Retry))
+	if e.Err != nil {
+		message += ": " + e.Err.Error()
+	}
+	return message
+}
+
+func (e *PartialError) Unwrap() error {
+	return e.Err
+}
+
+func (e *PartialError) Accepted(sent []Heartbeat) []Heartbeat {
+	failed := make(map[string]bool, len(e.Retry)+len(e.Rejected))
+	for _, hb := range e.Retry {
+		failed[bulkKey(hb)] = true
+	}
+	for _, rejection := range e.Rejected {
+		failed[bulkKey(rejection.Heartbeat)] = true
+	}
+
+	var accepted []Heartbeat
+	for _, hb := range sent {
+		if !failed[bulkKey(hb)] {
+			accepted = append(accepted, hb)
+		}
+	}
+	return accepted
+}
+
+func (e *PartialError) add(other *PartialError) {
+	e.Retry = append(e.Retry, other.Retry...)
+	e.Rejected = append(e.Rejected, other.Rejected...)
+	if other.Err != nil {
+		e.Err = errors.Join(e.Err, other.Err)
+	}
+}
+
+func (e *PartialError) orNil() error {
+	if len(e.Retry) == 0 && len(e.Rejected) == 0 {
+		return e.Err
+	}
+	return e
+}
+
+func partialFailure(heartbeats []Heartbeat, err error) *PartialError {
+	var partial *PartialError
+	if errors.As(err, &partial) {
+		return partial
+	}
+	if _, retry := RetryDelay(err); retry {
+		return &PartialError{Retry: heartbeats, Err: err}
+	}
+
+	status := 0
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		status = apiErr.StatusCode
+	}
+	failure := &PartialError{Err: err}
+	for _, hb := range heartbeats {
+		failure.Rejected = append(failure.Rejected, Rejection{Heartbeat: hb, Status: status, Reason: err.Error()})
+	}
+	return failure
+}
+
+func parseBulkResponse(heartbeats []Heartbeat, response json.RawMessage) *PartialError {
+	var body struct {
+		Responses []json.RawMessage `json:"responses"`
+	}
+	if json.Unmarshal(response, &body) != nil || len(body.Responses) != len(heartbeats) {
+		return &PartialError{}
+	}
+
+	failure := &PartialError{}
+	for i, raw := range body.Responses {
+		var entry []json.RawMessage
+		var status int
+		if json.Unmarshal(raw, &entry) != nil || len(entry) != 2 || json.Unmarshal(entry[1], &status) != nil {
+			continue
+		}
+		switch {
+		case status >= 200 && status <= 299:
+		case status == http.StatusTooManyRequests || status >= 500:
+			failure.Retry = append(failure.Retry, heartbeats[i])
+		default:
+			failure.Rejected = append(failure.Rejected, Rejection{Heartbeat: heartbeats[i], Status: status, Reason: bulkReason(entry[0], status)})
+		}
+	}
+	if len(failure.Retry) > 0 {
+		failure.Err = fmt.Errorf("hackatime api: %d heartbeats failed on the server", len(failure.Retry))
+	}
+	return failure
+}
+
+func bulkReason(data json.RawMessage, status int) string {
+	var body struct {
+		Error  string          `json:"error"`
+		Errors json.RawMessage `json:"errors"`
+	}
+	json.Unmarshal(data, &body)
+	switch {
+	case body.Error != "":
+		return body.Error
+	case len(body.Errors) > 0 && string(body.Errors) != "null":
+		return string(body.Errors)
+	default:
+		return http.StatusText(status)
+	}
+}
+
+func bulkKey(hb Heartbeat) string {
+	return fmt.Sprintf("%s@%.3f", hb.Entity, hb.Time)
+}
@@ -0,0 +1,287 @@
+package hackatime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultApiUrl       = "https://hackatime.hackclub.com/api/hackatime/v1"
+	cliTimeoutSecs      = 10
+	cliBatchTimeoutSecs = 30
+	windowsMaxPath      = 260
+)
+
+type CLIOptions struct {
+	ApiKey                string
+	ApiUrl                string
+	ExcludeUnknownProject bool
+	ConfigFile            string
+	LogFile               string
+	Timeout               time.Duration
+}
+
+type cliHeartbeat struct {
+	Entity            string  `json:"entity"`
+	EntityType        string  `json:"entity_type"`
+	Category          string  `json:"category,omitempty"`
+	Time              float64 `json:"time"`
+	LineNumber        int     `json:"lineno,omitempty"`
+	CursorPos         int     `json:"cursorpos,omitempty"`
+	Lines             int     `json:"lines,omitempty"`
+	Language          string  `json:"language,omitempty"`
+	AlternateLanguage string  `json:"alternate_language,omitempty"`
+	AlternateProject  string  `json:"alternate_project,omitempty"`
+	ProjectFolder     string  `json:"project_folder,omitempty"`
+	IsWrite           bool    `json:"is_write,omitempty"`
+	IsUnsaved         bool    `json:"is_unsaved_entity,omitempty"`
+	LocalFile         string  `json:"local_file,omitempty"`
+	LineAdditions     int     `json:"line_additions,omitempty"`
+	LineDeletions     int     `json:"line_deletions,omitempty"`
+	AILineChanges     int     `json:"ai_line_changes,omitempty"`
+	HumanLineChanges  int     `json:"human_line_changes,omitempty"`
+}
+
+func RunCLI(runner Runner, cliPath string, args []string, stdin []byte) error {
+	return runCLI(runner, cliPath, args, stdin, 0)
+}
+
+func runCLI(runner Runner, cliPath string, args []string, stdin []byte, timeout time.Duration) error {
+	if cliPath == "" {
+		return errors.New("wakatime-cli path not provided")
+	}
+
+	if timeout <= 0 {
+		timeout = cliTimeoutSecs * time.Second
+	}
+	if stdin != nil {
+		timeout = max(timeout, cliBatchTimeoutSecs*time.Second)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := runner.Run(ctx, cliPath, args, stdin)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("wakatime-cli timed out after %s: %w", timeout, context.DeadlineExceeded)
+	}
+	return err
+}
+
+func NewCLISender(runner Runner, cliPath string, options func() CLIOptions) SendFunc {
+	return func(heartbeats []Heartbeat) error {
+		if len(heartbeats) == 0 {
+			return nil
+		}
+
+		opts := options()
+		args, stdin, err := CLIBatch(heartbeats, opts)
+		if err != nil {
+			return err
+		}
+		return runCLI(runner, cliPath, args, stdin, opts.Timeout)
+	}
+}
+
+func CLIBatch(heartbeats []Heartbeat, opts CLIOptions) ([]string, []byte, error) {
+	args := CLIArgs(heartbeats[0], opts)
+	if len(heartbeats) == 1 {
+		return args, nil, nil
+	}
+
+	extra := make([]cliHeartbeat, 0, len(heartbeats)-1)
+	for _, hb := range heartbeats[1:] {
+		extra = append(extra, toCLIHeartbeat(hb))
+	}
+
+	stdin, err := json.Marshal(extra)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(args, "--extra-heartbeats"), stdin, nil
+}
+
+func toCLIHeartbeat(hb Heartbeat) cliHeartbeat {
+	out := cliHeartbeat{
+		Entity:           windowsLongPath(hb.Entity),
+		EntityType:       hb.EntityType,
+		Category:         hb.Category,
+		Time:             hb.Time,
+		Lines:            hb.Lines,
+		AlternateProject: hb.AlternateProject,
+		ProjectFolder:    hb.ProjectFolder,
+		IsWrite:          hb.IsWrite,
+		IsUnsaved:        hb.IsUnsaved,
+		LineAdditions:    hb.LineAdditions,
+		LineDeletions:    hb.LineDeletions,
+		AILineChanges:    hb.AILineChanges,
+		HumanLineChanges: hb.HumanLineChanges,
+	}
+	if hb.LocalFile != "" {
+		out.LocalFile = windowsLongPath(hb.LocalFile)
+	}
+	if hb.LineNumber > 0 {
+		out.LineNumber = hb.LineNumber
+		out.CursorPos = hb.CursorPos
+	}
+
+	if language := WakatimeLanguage(hb.Language); language != "" {
+		if filepath.Ext(hb.Entity) == "" {
+			out.Language = language
+		} else {
+			out.AlternateLanguage = language
+		}
+	}
+	return out
+}
+
+func CLIArgs(hb Heartbeat, opts CLIOptions) []string {
+	args := []string{}
+
+	if hb.EntityType == "" || hb.EntityType == "file" {
+		args = append(args, "--entity", windowsLongPath(hb.Entity))
+	} else {
+		args = append(args, "--entity", hb.Entity, "--entity-type", hb.EntityType)
+	}
+	args = append(args, "--time", fmt.Sprintf("%.3f", hb.Time))
+	args = append(args, "--plugin", hb.Plugin)
+	if hb.LineNumber > 0 {
+		args = append(args, "--lineno", strconv.Itoa(hb.LineNumber))
+		args = append(args, "--cursorpos", strconv.Itoa(hb.CursorPos))
+	}
+	if hb.Lines > 0 {
+		args = append(args, "--lines-in-file", strconv.Itoa(hb.Lines))
+	}
+
+	if language := WakatimeLanguage(hb.Language); language != "" {
+		if filepath.Ext(hb.Entity) == "" {
+			args = append(args, "--language", language)
+		} else {
+			args = append(args, "--alternate-language", language)
+		}
+	}
+
+	if hb.Category != "" {
+		args = append(args, "--category", hb.Category)
+	}
+
+	if opts.ApiKey != "" {
+		args = append(args, "--key", opts.ApiKey)
+	}
+	if opts.ApiUrl != "" {
+		args = append(args, "--api-url", opts.ApiUrl)
+	} else {
+		args = append(args, "--api-url", DefaultApiUrl)
+	}
+
+	if hb.AlternateProject != "" {
+		args = append(args, "--alternate-project", hb.AlternateProject)
+	}
+	if hb.ProjectFolder != "" {
+		args = append(args, "--project-folder", hb.ProjectFolder)
+	}
+
+	if hb.IsWrite {
+		args = append(args, "--write")
+	}
+
+	if hb.LineAdditions > 0 {
+		args = append(args, "--line-additions", strconv.Itoa(hb.LineAdditions))
+	}
+	if hb.LineDeletions > 0 {
+		args = append(args, "--line-deletions", strconv.Itoa(hb.LineDeletions))
+	}
+	if hb.AILineChanges > 0 {
+		args = append(args, "--ai-line-changes", strconv.Itoa(hb.AILineChanges))
+	}
+	if hb.HumanLineChanges > 0 {
+		args = append(args, "--human-line-changes", strconv.Itoa(hb.HumanLineChanges))
+	}
+
+	if opts.ExcludeUnknownProject {
+		args = append(args, "--exclude-unknown-project")
+	}
+
+	if runtime.GOOS == "windows" {
+		if opts.ConfigFile != "" {
+			args = append(args, "--config", opts.ConfigFile)
+		}
+		if opts.LogFile != "" {
+			args = append(args, "--log-file", opts.LogFile)
+		}
+	}
+
+	if hb.IsUnsaved {
+		args = append(args, "--is-unsaved-entity")
+	}
+
+	if hb.Hostname != "" {
+		args = append(args, "--hostname", hb.Hostname)
+	}
+
+	if hb.LocalFile != "" {
+		args = append(args, "--local-file", windowsLongPath(hb.LocalFile))
+	}
+
+	return args
+}
+
+func RedactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+	for i := 1; i < len(redacted); i++ {
+		if redacted[i-1] == "--key" {
+			redacted[i] = redactApiKey(redacted[i])
+		}
+	}
+	return redacted
+}
+
+func redactApiKey(apiKey string) string {
+	if len(apiKey) <= 4 {
+		return "****"
+	}
+	return strings.Repeat("*", len(apiKey)-4) + apiKey[len(apiKey)-4:]
+}
+
+func ValidateCLIPath(cliPath string) error {
+	if cliPath == "" {
+		return errors.New("wakatime-cli path not provided")
+	}
+
+	info, err := os.Stat(cliPath)
+	if err != nil {
+		return fmt.Errorf("wakatime-cli not found at %s", cliPath)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("wakatime-cli path %s is a directory", cliPath)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0111 == 0 {
+		return fmt.Errorf("wakatime-cli at %s is not executable", cliPath)
+	}
+	if runtime.GOOS == "windows" && !strings.HasSuffix(strings.ToLower(cliPath), ".exe") {
+		return fmt.Errorf("wakatime-cli at %s is not an .exe", cliPath)
+	}
+	return nil
+}
+
+func windowsLongPath(path string) string {
+	if runtime.GOOS != "windows" || len(path) < windowsMaxPath || strings.HasPrefix(path, `\\?\`) {
+		return path
+	}
+	if strings.HasPrefix(path, `\\`) {
+		return `\\?\UNC\` + path[2:]
+	}
+	if filepath.IsAbs(path) {
+		return `\\?\` + path
+	}
+	return path
+}
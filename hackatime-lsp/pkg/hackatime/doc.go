@@ -0,0 +1,4 @@
+// Package hackatime queues, throttles and sends heartbeats to Hackatime
+// through wakatime-cli. It has no dependency on the language server, so
+// other editor integrations can reuse the same pipeline.
+package hackatime
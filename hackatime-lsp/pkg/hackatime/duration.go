@@ -0,0 +1,102 @@
+package hackatime
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+const DefaultKeystrokeTimeout = 15 * time.Minute
+
+type Duration struct {
+	Project  string
+	Language string
+	Category string
+	Start    time.Time
+	Duration time.Duration
+}
+
+func (d Duration) End() time.Time {
+	return d.Start.Add(d.Duration)
+}
+
+func Durations(heartbeats []Heartbeat, timeout time.Duration) []Duration {
+	if timeout <= 0 {
+		timeout = DefaultKeystrokeTimeout
+	}
+
+	sorted := slices.Clone(heartbeats)
+	slices.SortStableFunc(sorted, func(a, b Heartbeat) int {
+		return cmp.Compare(a.Time, b.Time)
+	})
+
+	var durations []Duration
+	for i, hb := range sorted {
+		start := heartbeatTime(hb)
+		if i > 0 {
+			prev := sorted[i-1]
+			current := &durations[len(durations)-1]
+			gap := start.Sub(heartbeatTime(prev))
+			if gap < timeout {
+				current.Duration += gap
+				if sameDuration(*current, hb) {
+					continue
+				}
+			}
+		}
+		durations = append(durations, Duration{
+			Project:  hb.AlternateProject,
+			Language: hb.Language,
+			Category: hb.Category,
+			Start:    start,
+		})
+	}
+	return durations
+}
+
+func sameDuration(d Duration, hb Heartbeat) bool {
+	return d.Project == hb.AlternateProject && d.Language == hb.Language && d.Category == hb.Category
+}
+
+func TotalDuration(heartbeats []Heartbeat, timeout time.Duration) time.Duration {
+	var total time.Duration
+	for _, d := range Durations(heartbeats, timeout) {
+		total += d.Duration
+	}
+	return total
+}
+
+func DurationsBy(durations []Duration, key func(d Duration) string) map[string]time.Duration {
+	totals := make(map[string]time.Duration)
+	for _, d := range durations {
+		totals[key(d)] += d.Duration
+	}
+	return totals
+}
+
+func heartbeatTime(hb Heartbeat) time.Time {
+	return time.UnixMilli(int64(hb.Time * 1000))
+}
+
+func DurationItems(totals map[string]time.Duration) []SummaryItem {
+	var total time.Duration
+	for _, d := range totals {
+		total += d
+	}
+
+	items := make([]SummaryItem, 0, len(totals))
+	for name, d := range totals {
+		item := SummaryItem{Name: name, TotalSeconds: d.Seconds(), Text: FormatDuration(d)}
+		if total > 0 {
+			item.Percent = float64(d) / float64(total) * 100
+		}
+		items = append(items, item)
+	}
+	slices.SortFunc(items, func(a, b SummaryItem) int {
+		if c := cmp.Compare(b.TotalSeconds, a.TotalSeconds); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return items
+}
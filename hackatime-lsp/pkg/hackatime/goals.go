@@ -0,0 +1,45 @@
+package hackatime
+
+import (
+	"context"
+	"time"
+)
+
+type GoalDay struct {
+	ActualSeconds float64      `json:"actual_seconds"`
+	GoalSeconds   float64      `json:"goal_seconds"`
+	Range         SummaryRange `json:"range"`
+	RangeStatus   string       `json:"range_status"`
+}
+
+type Goal struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Delta     string    `json:"delta"`
+	Seconds   float64   `json:"seconds"`
+	Status    string    `json:"status"`
+	IsEnabled bool      `json:"is_enabled"`
+	IsSnoozed bool      `json:"is_snoozed"`
+	ChartData []GoalDay `json:"chart_data"`
+}
+
+func (g Goal) Today() (GoalDay, bool) {
+	if len(g.ChartData) == 0 {
+		return GoalDay{}, false
+	}
+	day := g.ChartData[len(g.ChartData)-1]
+	if day.Range.Date != "" && day.Range.Date != time.Now().Format(dateLayout) {
+		return GoalDay{}, false
+	}
+	return day, true
+}
+
+func (c *Client) Goals(ctx context.Context) ([]Goal, error) {
+	var goals struct {
+		Data []Goal `json:"data"`
+	}
+	if err := c.get(ctx, "/users/current/goals", nil, &goals); err != nil {
+		return nil, err
+	}
+	return goals.Data, nil
+}
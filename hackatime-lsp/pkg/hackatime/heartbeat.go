@@ -0,0 +1,46 @@
+package hackatime
+
+import "time"
+
+const (
+	Interval          = 2 * time.Minute
+	duplicateWindowMs = 1000
+)
+
+type Heartbeat struct {
+	Entity           string  `json:"entity"`
+	EntityType       string  `json:"entity_type"`
+	Category         string  `json:"category"`
+	Time             float64 `json:"time"`
+	Plugin           string  `json:"plugin"`
+	LineNumber       int     `json:"lineno"`
+	CursorPos        int     `json:"cursorpos"`
+	Lines            int     `json:"lines_in_file"`
+	AlternateProject string  `json:"alternate_project"`
+	ProjectFolder    string  `json:"project_folder"`
+	IsWrite          bool    `json:"is_write"`
+	IsUnsaved        bool    `json:"is_unsaved_entity"`
+	LocalFile        string  `json:"local_file,omitempty"`
+	LineAdditions    int     `json:"line_additions,omitempty"`
+	LineDeletions    int     `json:"line_deletions,omitempty"`
+	AILineChanges    int     `json:"ai_line_changes,omitempty"`
+	HumanLineChanges int     `json:"human_line_changes,omitempty"`
+	CharsTyped       int     `json:"chars_typed,omitempty"`
+	CharsDeleted     int     `json:"chars_deleted,omitempty"`
+	EditEvents       int     `json:"edit_events,omitempty"`
+	Account          string  `json:"account,omitempty"`
+	Branch           string
+	Language         string
+	Hostname         string
+	UserAgent        string
+}
+
+func IsDuplicate(prev, hb Heartbeat) bool {
+	if prev.Entity == "" {
+		return false
+	}
+	return prev.IsWrite == hb.IsWrite &&
+		prev.LineNumber == hb.LineNumber &&
+		prev.CursorPos == hb.CursorPos &&
+		hb.Time-prev.Time < duplicateWindowMs/1000.0
+}
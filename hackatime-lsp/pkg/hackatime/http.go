@@ -0,0 +1,125 @@
+package hackatime
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+const (
+	defaultConnectTimeout = 5 * time.Second
+	defaultReadTimeout    = 10 * time.Second
+)
+
+type HTTPOptions struct {
+	Proxy          string
+	SSLCertsFile   string
+	NoSSLVerify    bool
+	ConnectTimeout time.Duration
+	ReadTimeout    time.Duration
+}
+
+func NewHTTPClient(opts HTTPOptions) (*http.Client, error) {
+	connectTimeout := cmp.Or(opts.ConnectTimeout, defaultConnectTimeout)
+	readTimeout := cmp.Or(opts.ReadTimeout, defaultReadTimeout)
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFromEnvironment
+	transport.DialContext = (&net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = connectTimeout
+	transport.ResponseHeaderTimeout = readTimeout
+	if opts.Proxy != "" {
+		proxy, err := ParseProxy(opts.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	if opts.SSLCertsFile != "" || opts.NoSSLVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: opts.NoSSLVerify}
+	}
+	if opts.SSLCertsFile != "" {
+		roots, err := LoadCertPool(opts.SSLCertsFile)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig.RootCAs = roots
+	}
+	return &http.Client{Timeout: connectTimeout + readTimeout, Transport: transport}, nil
+}
+
+func ParseProxy(proxy string) (*url.URL, error) {
+	if strings.Contains(proxy, `\`) {
+		return nil, errors.New("NTLM proxies (domain\\user:password@host) are not supported, use a proxy URL with basic auth instead")
+	}
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %q: %w", proxy, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, use http, https or socks5", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q: missing host", proxy)
+	}
+	return u, nil
+}
+
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading ssl_certs_file: %w", err)
+	}
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	if !roots.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("ssl_certs_file %s contains no PEM certificates", path)
+	}
+	return roots, nil
+}
+
+func IsTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+func proxyFromEnvironment(req *http.Request) (*url.URL, error) {
+	proxies := httpproxy.FromEnvironment()
+	if all := firstEnv("ALL_PROXY", "all_proxy"); all != "" {
+		if proxies.HTTPProxy == "" {
+			proxies.HTTPProxy = all
+		}
+		if proxies.HTTPSProxy == "" {
+			proxies.HTTPSProxy = all
+		}
+	}
+	return proxies.ProxyFunc()(req.URL)
+}
+
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
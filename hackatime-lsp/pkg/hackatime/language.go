@@ -0,0 +1,74 @@
+package hackatime
+
+import "strings"
+
+var wakatimeLanguages = map[string]string{
+	"bash":            "Bash",
+	"shellscript":     "Bash",
+	"shell script":    "Bash",
+	"c":               "C",
+	"cpp":             "C++",
+	"c++":             "C++",
+	"csharp":          "C#",
+	"c#":              "C#",
+	"css":             "CSS",
+	"dart":            "Dart",
+	"dockerfile":      "Docker",
+	"elixir":          "Elixir",
+	"elm":             "Elm",
+	"erlang":          "Erlang",
+	"fish":            "Fish",
+	"fsharp":          "F#",
+	"gleam":           "Gleam",
+	"go":              "Go",
+	"go.mod":          "Go Module",
+	"gomod":           "Go Module",
+	"graphql":         "GraphQL",
+	"haskell":         "Haskell",
+	"html":            "HTML",
+	"java":            "Java",
+	"javascript":      "JavaScript",
+	"javascriptreact": "JSX",
+	"jsx":             "JSX",
+	"json":            "JSON",
+	"jsonc":           "JSON",
+	"julia":           "Julia",
+	"kotlin":          "Kotlin",
+	"latex":           "TeX",
+	"lua":             "Lua",
+	"make":            "Makefile",
+	"makefile":        "Makefile",
+	"markdown":        "Markdown",
+	"nix":             "Nix",
+	"ocaml":           "OCaml",
+	"php":             "PHP",
+	"plaintext":       "Text",
+	"plain text":      "Text",
+	"python":          "Python",
+	"r":               "R",
+	"ruby":            "Ruby",
+	"rust":            "Rust",
+	"scala":           "Scala",
+	"scss":            "SCSS",
+	"sql":             "SQL",
+	"svelte":          "Svelte",
+	"swift":           "Swift",
+	"toml":            "TOML",
+	"tsx":             "TSX",
+	"typescript":      "TypeScript",
+	"typescriptreact": "TSX",
+	"vue":             "Vue.js",
+	"xml":             "XML",
+	"yaml":            "YAML",
+	"zig":             "Zig",
+}
+
+func WakatimeLanguage(languageId string) string {
+	if languageId == "" {
+		return ""
+	}
+	if language, exists := wakatimeLanguages[strings.ToLower(languageId)]; exists {
+		return language
+	}
+	return languageId
+}
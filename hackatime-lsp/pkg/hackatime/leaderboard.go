@@ -0,0 +1,99 @@
+package hackatime
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+const (
+	LeaderboardDaily  = "daily"
+	LeaderboardWeekly = "weekly"
+)
+
+type User struct {
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+}
+
+type LeaderboardEntry struct {
+	Rank         int     `json:"rank"`
+	TotalSeconds float64 `json:"total_seconds"`
+	User         User    `json:"user"`
+}
+
+type Leaderboard struct {
+	Period    string             `json:"period"`
+	StartDate string             `json:"start_date"`
+	EndDate   string             `json:"end_date"`
+	Entries   []LeaderboardEntry `json:"entries"`
+}
+
+type LeaderboardRank struct {
+	Period       string  `json:"period"`
+	Rank         int     `json:"rank"`
+	Of           int     `json:"of"`
+	TotalSeconds float64 `json:"total_seconds"`
+}
+
+func (c *Client) CurrentUser(ctx context.Context) (*User, error) {
+	var user struct {
+		Data User `json:"data"`
+	}
+	if err := c.get(ctx, "/users/current", nil, &user); err != nil {
+		return nil, err
+	}
+	return &user.Data, nil
+}
+
+func (c *Client) Leaderboard(ctx context.Context, period string) (*Leaderboard, error) {
+	query := url.Values{}
+	if period != "" {
+		query.Set("period", period)
+	}
+
+	var leaderboard Leaderboard
+	if err := c.get(ctx, "/leaderboard", query, &leaderboard); err != nil {
+		return nil, err
+	}
+	if leaderboard.Period == "" {
+		leaderboard.Period = period
+	}
+	for i := range leaderboard.Entries {
+		if leaderboard.Entries[i].Rank == 0 {
+			leaderboard.Entries[i].Rank = i + 1
+		}
+	}
+	return &leaderboard, nil
+}
+
+func (l *Leaderboard) RankOf(username string) (LeaderboardRank, bool) {
+	for _, entry := range l.Entries {
+		if strings.EqualFold(entry.User.Username, username) {
+			return LeaderboardRank{
+				Period:       l.Period,
+				Rank:         entry.Rank,
+				Of:           len(l.Entries),
+				TotalSeconds: entry.TotalSeconds,
+			}, true
+		}
+	}
+	return LeaderboardRank{}, false
+}
+
+func (c *Client) LeaderboardRank(ctx context.Context, period string) (*Leaderboard, *LeaderboardRank, error) {
+	user, err := c.CurrentUser(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	leaderboard, err := c.Leaderboard(ctx, period)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rank, ok := leaderboard.RankOf(user.Username)
+	if !ok {
+		return leaderboard, nil, nil
+	}
+	return leaderboard, &rank, nil
+}
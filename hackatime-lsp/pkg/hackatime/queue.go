@@ -0,0 +1,353 @@
+package hackatime
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBatchInterval = 120 * time.Second
+	DefaultQueueSize     = 100
+	DefaultMemoryLimit   = 1000
+
+	queueFlushKey = "queue"
+	retryInterval = 30 * time.Second
+)
+
+type SendFunc func(heartbeats []Heartbeat) error
+
+type Queue struct {
+	send SendFunc
+
+	mutex          sync.Mutex
+	heartbeats     []Heartbeat
+	scheduler      *Scheduler
+	paused         bool
+	closed         bool
+	sending        bool
+	inFlight       sync.WaitGroup
+	lastSent       time.Time
+	retryAt        time.Time
+	offline        bool
+	resynced       int
+	onConnectivity func(online bool, synced int)
+	batchInterval  time.Duration
+	maxSize        int
+	store          *Store
+	memoryLimit    int
+}
+
+func NewQueue(send SendFunc) *Queue {
+	return &Queue{
+		send:          send,
+		scheduler:     NewScheduler(),
+		batchInterval: DefaultBatchInterval,
+		maxSize:       DefaultQueueSize,
+	}
+}
+
+func (q *Queue) Configure(batchInterval time.Duration, maxSize int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.batchInterval = batchInterval
+	q.maxSize = maxSize
+}
+
+func (q *Queue) SpillTo(store *Store, memoryLimit int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.store = store
+	q.memoryLimit = memoryLimit
+	if q.hasSpilled() {
+		q.schedule()
+	}
+}
+
+func (q *Queue) Add(hb Heartbeat) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.closed && q.store != nil {
+		q.store.Append([]Heartbeat{hb})
+		return
+	}
+
+	q.heartbeats = append(q.heartbeats, hb)
+	if q.store != nil && q.memoryLimit > 0 && len(q.heartbeats) > q.memoryLimit {
+		q.spillLocked()
+	}
+
+	if len(q.heartbeats) >= q.maxSize {
+		go q.Flush()
+	} else if len(q.heartbeats) == 1 {
+		q.schedule()
+	}
+}
+
+func (q *Queue) spillLocked() {
+	overflow := len(q.heartbeats) - q.memoryLimit/2
+	if err := q.store.Append(q.heartbeats[:overflow]); err != nil {
+		return
+	}
+	q.heartbeats = append([]Heartbeat(nil), q.heartbeats[overflow:]...)
+}
+
+func (q *Queue) hasSpilled() bool {
+	return q.store != nil && q.store.Pending()
+}
+
+func (q *Queue) schedule() {
+	if q.paused || q.closed {
+		return
+	}
+	q.scheduler.Schedule(queueFlushKey, max(q.batchInterval, time.Until(q.retryAt)), q.Flush)
+}
+
+func (q *Queue) Flush() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.flushLocked()
+}
+
+func (q *Queue) flushLocked() {
+	if q.sending || q.closed || (len(q.heartbeats) == 0 && !q.hasSpilled()) {
+		return
+	}
+	if time.Now().Before(q.retryAt) {
+		q.schedule()
+		return
+	}
+
+	pending := q.heartbeats
+	q.heartbeats = nil
+	q.sending = true
+	q.lastSent = time.Now()
+
+	q.inFlight.Add(1)
+	go func() {
+		defer q.inFlight.Done()
+		sent, err := q.sendSpilled()
+		requeue := pending
+		if _, retry := RetryDelay(err); !retry && len(pending) > 0 {
+			err = q.send(pending)
+			requeue = retryable(err, pending)
+			var partial *PartialError
+			switch {
+			case errors.As(err, &partial):
+				sent += len(partial.Accepted(pending))
+			case err == nil:
+				sent += len(pending)
+			}
+		}
+
+		var notify func()
+		defer func() {
+			if notify != nil {
+				notify()
+			}
+		}()
+		q.mutex.Lock()
+		defer q.mutex.Unlock()
+
+		q.sending = false
+		wait, retry := RetryDelay(err)
+		if retry {
+			q.retryAt = time.Now().Add(wait)
+			q.heartbeats = append(requeue, q.heartbeats...)
+			if q.store != nil && q.memoryLimit > 0 && len(q.heartbeats) > q.memoryLimit {
+				q.spillLocked()
+			}
+		}
+		notify = q.trackConnectivity(IsOffline(err), retry, sent)
+		if q.closed {
+			return
+		}
+		if len(q.heartbeats) >= q.maxSize || q.hasSpilled() || (q.offline && !retry && len(q.heartbeats) > 0) {
+			q.flushLocked()
+		} else if len(q.heartbeats) > 0 {
+			q.schedule()
+		}
+	}()
+}
+
+func (q *Queue) trackConnectivity(offline, retry bool, sent int) func() {
+	f := q.onConnectivity
+	switch {
+	case offline && !q.offline:
+		q.offline = true
+		q.resynced = 0
+		if f != nil {
+			return func() { f(false, 0) }
+		}
+	case !offline && q.offline:
+		q.resynced += sent
+		if retry || len(q.heartbeats) > 0 || q.hasSpilled() {
+			return nil
+		}
+		q.offline = false
+		synced := q.resynced
+		if f != nil {
+			return func() { f(true, synced) }
+		}
+	}
+	return nil
+}
+
+func (q *Queue) sendSpilled() (int, error) {
+	q.mutex.Lock()
+	store, maxSize := q.store, q.maxSize
+	q.mutex.Unlock()
+
+	if store == nil {
+		return 0, nil
+	}
+	spilled, _ := store.Take(maxSize)
+	if len(spilled) == 0 {
+		return 0, nil
+	}
+	err := q.send(spilled)
+	if _, retry := RetryDelay(err); retry {
+		store.Append(retryable(err, spilled))
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(spilled), nil
+}
+
+func RetryDelay(err error) (time.Duration, bool) {
+	if wait, limited := RetryAfter(err); limited {
+		return wait, true
+	}
+	if IsOffline(err) {
+		return retryInterval, true
+	}
+	var partial *PartialError
+	if errors.As(err, &partial) && len(partial.Retry) > 0 {
+		return retryInterval, true
+	}
+	return 0, false
+}
+
+func retryable(err error, sent []Heartbeat) []Heartbeat {
+	var partial *PartialError
+	if errors.As(err, &partial) {
+		return partial.Retry
+	}
+	return sent
+}
+
+func (q *Queue) Close() error {
+	q.mutex.Lock()
+	q.scheduler.Stop()
+	q.closed = true
+	q.mutex.Unlock()
+
+	q.inFlight.Wait()
+
+	q.mutex.Lock()
+	pending, store := q.heartbeats, q.store
+	backingOff := q.offline || time.Now().Before(q.retryAt)
+	q.heartbeats = nil
+	q.mutex.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+	if backingOff && store != nil {
+		return store.Append(pending)
+	}
+	err := q.send(pending)
+	if err == nil || store == nil {
+		return err
+	}
+	return store.Append(retryable(err, pending))
+}
+
+func (q *Queue) Persist() (int, error) {
+	q.mutex.Lock()
+	q.scheduler.Stop()
+	q.closed = true
+	q.mutex.Unlock()
+
+	q.inFlight.Wait()
+
+	q.mutex.Lock()
+	pending, store := q.heartbeats, q.store
+	q.heartbeats = nil
+	q.mutex.Unlock()
+
+	if len(pending) == 0 {
+		return 0, nil
+	}
+	if store == nil {
+		return 0, q.send(pending)
+	}
+	return len(pending), store.Append(pending)
+}
+
+func (q *Queue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return len(q.heartbeats)
+}
+
+func (q *Queue) OnConnectivity(f func(online bool, synced int)) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.onConnectivity = f
+}
+
+func (q *Queue) Offline() bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.offline
+}
+
+func (q *Queue) Paused() bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.paused
+}
+
+func (q *Queue) Spilled() bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.hasSpilled()
+}
+
+func (q *Queue) Pause() bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.paused {
+		return false
+	}
+	q.paused = true
+	q.scheduler.Cancel(queueFlushKey)
+	return true
+}
+
+func (q *Queue) Resume() bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if !q.paused {
+		return false
+	}
+	q.paused = false
+
+	if len(q.heartbeats) > 0 {
+		q.schedule()
+	}
+	return true
+}
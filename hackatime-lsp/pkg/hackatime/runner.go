@@ -0,0 +1,70 @@
+package hackatime
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"sync"
+)
+
+type Runner interface {
+	Run(ctx context.Context, name string, args []string, stdin []byte) error
+	Output(ctx context.Context, name string, args []string) ([]byte, error)
+}
+
+type ExecRunner struct{}
+
+func (ExecRunner) Run(ctx context.Context, name string, args []string, stdin []byte) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	return cmd.Run()
+}
+
+func (ExecRunner) Output(ctx context.Context, name string, args []string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).Output()
+}
+
+type Call struct {
+	Name  string
+	Args  []string
+	Stdin []byte
+}
+
+type MockRunner struct {
+	Record func(call Call)
+	Stdout []byte
+
+	mutex sync.Mutex
+	calls []Call
+}
+
+func (m *MockRunner) Run(ctx context.Context, name string, args []string, stdin []byte) error {
+	call := Call{Name: name, Args: args, Stdin: stdin}
+
+	m.mutex.Lock()
+	m.calls = append(m.calls, call)
+	m.mutex.Unlock()
+
+	if m.Record != nil {
+		m.Record(call)
+	}
+	return nil
+}
+
+func (m *MockRunner) Output(ctx context.Context, name string, args []string) ([]byte, error) {
+	if err := m.Run(ctx, name, args, nil); err != nil {
+		return nil, err
+	}
+	return m.Stdout, nil
+}
+
+func (m *MockRunner) Calls() []Call {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	calls := make([]Call, len(m.calls))
+	copy(calls, m.calls)
+	return calls
+}
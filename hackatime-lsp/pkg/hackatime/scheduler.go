@@ -0,0 +1,89 @@
+package hackatime
+
+import (
+	"sync"
+	"time"
+)
+
+type Scheduler struct {
+	mutex   sync.Mutex
+	timers  map[string]*time.Timer
+	stopped bool
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		timers: make(map[string]*time.Timer),
+	}
+}
+
+func (s *Scheduler) Schedule(key string, delay time.Duration, f func()) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.stopped || s.timers[key] != nil {
+		return false
+	}
+	s.start(key, delay, f)
+	return true
+}
+
+func (s *Scheduler) Debounce(key string, delay time.Duration, f func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.stopped {
+		return
+	}
+	if timer := s.timers[key]; timer != nil {
+		timer.Stop()
+	}
+	s.start(key, delay, f)
+}
+
+func (s *Scheduler) start(key string, delay time.Duration, f func()) {
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		s.mutex.Lock()
+		if s.timers[key] != timer {
+			s.mutex.Unlock()
+			return
+		}
+		delete(s.timers, key)
+		s.mutex.Unlock()
+
+		f()
+	})
+	s.timers[key] = timer
+}
+
+func (s *Scheduler) Cancel(key string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	timer := s.timers[key]
+	if timer == nil {
+		return false
+	}
+	timer.Stop()
+	delete(s.timers, key)
+	return true
+}
+
+func (s *Scheduler) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.timers)
+}
+
+func (s *Scheduler) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for key, timer := range s.timers {
+		timer.Stop()
+		delete(s.timers, key)
+	}
+	s.stopped = true
+}
@@ -0,0 +1,149 @@
+package hackatime
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type Store struct {
+	mutex sync.Mutex
+	path  string
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+func (s *Store) Append(heartbeats []Heartbeat) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.appendLocked(heartbeats)
+}
+
+func (s *Store) appendLocked(heartbeats []Heartbeat) error {
+	if len(heartbeats) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, hb := range heartbeats {
+		if err := encoder.Encode(hb); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (s *Store) Take(n int) ([]Heartbeat, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var taken, rest []Heartbeat
+	err := s.claim(func(hb Heartbeat) {
+		if len(taken) < n {
+			taken = append(taken, hb)
+		} else {
+			rest = append(rest, hb)
+		}
+	})
+	if err != nil {
+		return taken, err
+	}
+	return taken, s.appendLocked(rest)
+}
+
+func (s *Store) Prune(keep func(hb Heartbeat) bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var kept []Heartbeat
+	err := s.claim(func(hb Heartbeat) {
+		if keep(hb) {
+			kept = append(kept, hb)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return s.appendLocked(kept)
+}
+
+func (s *Store) Read(keep func(hb Heartbeat) bool) ([]Heartbeat, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var heartbeats []Heartbeat
+	err = readHeartbeats(file, func(hb Heartbeat) {
+		if keep == nil || keep(hb) {
+			heartbeats = append(heartbeats, hb)
+		}
+	})
+	return heartbeats, err
+}
+
+func (s *Store) claim(each func(hb Heartbeat)) error {
+	claimed := fmt.Sprintf("%s.%d-%d", s.path, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(s.path, claimed); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer os.Remove(claimed)
+
+	file, err := os.Open(claimed)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return readHeartbeats(file, each)
+}
+
+func readHeartbeats(r io.Reader, each func(hb Heartbeat)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var hb Heartbeat
+		if err := json.Unmarshal(scanner.Bytes(), &hb); err != nil {
+			continue
+		}
+		each(hb)
+	}
+	return scanner.Err()
+}
+
+func (s *Store) Pending() bool {
+	info, err := os.Stat(s.path)
+	return err == nil && info.Size() > 0
+}
@@ -0,0 +1,85 @@
+package hackatime
+
+import (
+	"sync"
+	"time"
+)
+
+type Throttle struct {
+	mutex         sync.Mutex
+	lastEventTime map[string]time.Time
+	lastEntity    string
+	lastQueued    map[string]Heartbeat
+	lastPrune     time.Time
+	interval      time.Duration
+}
+
+func NewThrottle() *Throttle {
+	return &Throttle{
+		lastEventTime: make(map[string]time.Time),
+		lastQueued:    make(map[string]Heartbeat),
+		interval:      Interval,
+	}
+}
+
+func (t *Throttle) Allow(hb Heartbeat) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if IsDuplicate(t.lastQueued[hb.Entity], hb) {
+		return false
+	}
+
+	now := time.Now()
+	if now.Sub(t.lastPrune) >= t.interval {
+		t.prune(now)
+	}
+	lastTime, exists := t.lastEventTime[hb.Entity]
+
+	if hb.IsWrite || hb.Entity != t.lastEntity || !exists || now.Sub(lastTime) >= t.interval {
+		t.lastEventTime[hb.Entity] = now
+		t.lastEntity = hb.Entity
+		t.lastQueued[hb.Entity] = hb
+		return true
+	}
+	return false
+}
+
+func (t *Throttle) Interval() time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.interval
+}
+
+func (t *Throttle) SetInterval(interval time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.interval = interval
+}
+
+func (t *Throttle) Duplicate(hb Heartbeat) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return IsDuplicate(t.lastQueued[hb.Entity], hb)
+}
+
+func (t *Throttle) Forget(entity string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.lastEventTime, entity)
+	delete(t.lastQueued, entity)
+}
+
+func (t *Throttle) prune(now time.Time) {
+	for entity, lastTime := range t.lastEventTime {
+		if now.Sub(lastTime) >= t.interval {
+			delete(t.lastEventTime, entity)
+			delete(t.lastQueued, entity)
+		}
+	}
+	t.lastPrune = now
+}
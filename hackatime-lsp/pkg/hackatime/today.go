@@ -0,0 +1,47 @@
+package hackatime
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"time"
+)
+
+func TodayArgs(opts CLIOptions) []string {
+	args := []string{"--today"}
+
+	if opts.ApiKey != "" {
+		args = append(args, "--key", opts.ApiKey)
+	}
+	if opts.ApiUrl != "" {
+		args = append(args, "--api-url", opts.ApiUrl)
+	} else {
+		args = append(args, "--api-url", DefaultApiUrl)
+	}
+
+	if runtime.GOOS == "windows" {
+		if opts.ConfigFile != "" {
+			args = append(args, "--config", opts.ConfigFile)
+		}
+		if opts.LogFile != "" {
+			args = append(args, "--log-file", opts.LogFile)
+		}
+	}
+	return args
+}
+
+func Today(runner Runner, cliPath string, opts CLIOptions) (string, error) {
+	if cliPath == "" {
+		return "", errors.New("wakatime-cli path not provided")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cliTimeoutSecs*time.Second)
+	defer cancel()
+
+	output, err := runner.Output(ctx, cliPath, TodayArgs(opts))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}